@@ -0,0 +1,94 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/file"
+	"github.com/spf13/cobra"
+)
+
+func newAddCmd(c *command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "add [template name]",
+		Short: "Adds templates to .gitignore",
+		Long: `Adds the given [template name] to the templates already used
+in the .gitignore file of the current working directory and regenerates it.
+The templates in use are read from the managed block of the file or,
+if there is none, from the headers of a file previously generated by gig.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: c.addRunE,
+	}
+}
+
+func (c *command) addRunE(cmd *cobra.Command, args []string) error {
+	templates, err := c.layers.List()
+	if err != nil {
+		return err
+	}
+
+	qualified, err := c.layers.Qualified()
+	if err != nil {
+		return err
+	}
+
+	templates = append(templates, qualified...)
+
+	supported := make(map[string]bool, len(templates))
+
+	for _, t := range templates {
+		supported[file.Canon(t)] = true
+	}
+
+	for _, arg := range args {
+		if !supported[file.Canon(arg)] {
+			return errors.Errorf("cmd: %s is not a supported template", arg)
+		}
+	}
+
+	doc, items, err := readTemplates()
+	if err != nil {
+		return err
+	}
+
+	items = append(items, args...)
+
+	return c.writeIgnoreFile(doc, c.sortItems(unique(items)))
+}
+
+// unique removes the duplicates of items while keeping their order.
+func unique(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if seen[file.Canon(item)] {
+			continue
+		}
+
+		seen[file.Canon(item)] = true
+
+		result = append(result, item)
+	}
+
+	return result
+}
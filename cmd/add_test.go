@@ -0,0 +1,69 @@
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdd(t *testing.T) {
+	source, hash := newTemplatesSource(t)
+
+	g := newGig(t)
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte("/bin/\n"), 0600))
+
+	_, err := g.run(source, "gen", "--file", "go")
+	require.NoError(t, err)
+
+	// Templates already in use are not added twice.
+	_, err = g.run(source, "add", "Elm", "go")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`/bin/
+
+# gig:begin templates=Elm,go commit=%s
+
+### Elm ###
+elm-stuff
+
+### Go ###
+*.exe
+# gig:end
+`, hash), g.readFile(".gitignore"))
+}
+
+func TestAdd_Legacy(t *testing.T) {
+	source, hash := newTemplatesSource(t)
+
+	g := newGig(t)
+
+	// A .gitignore generated by an older gig only has the headers.
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte("\n### Go ###\n*.exe\n"), 0600))
+
+	_, err := g.run(source, "add", "elm")
+	require.NoError(t, err)
+	// It is migrated to a managed block, the names are taken from the headers.
+	assert.Equal(t, fmt.Sprintf(`# gig:begin templates=elm,Go commit=%s
+
+### Elm ###
+elm-stuff
+
+### Go ###
+*.exe
+# gig:end
+`, hash), g.readFile(".gitignore"))
+}
+
+func TestAdd_Unknown(t *testing.T) {
+	source, _ := newTemplatesSource(t)
+
+	g := newGig(t)
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte("/bin/\n"), 0600))
+
+	_, err := g.run(source, "add", "Cobol")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Cobol is not a supported template")
+	assert.Equal(t, "/bin/\n", g.readFile(".gitignore"))
+}
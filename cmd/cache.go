@@ -0,0 +1,357 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+func newCacheCmd(c *command) *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manages the template cache",
+		Long: `Manages the cache of the templates of --source.
+None of the subcommands clones the templates when they are not cached yet.`,
+		// The subcommands work with the cache directly and must not clone it.
+		PersistentPreRunE: c.sourceRunE,
+	}
+
+	infoCmd := &cobra.Command{
+		Use:          "info",
+		Short:        "Prints the location, size, commit, remote, and last fetch time of the cache",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         c.cacheInfoRunE,
+		Annotations:  map[string]string{readOnlyAnnotation: ""},
+	}
+
+	infoCmd.Flags().BoolVarP(&c.cacheJSON, "json", "", false, "print the information as JSON")
+
+	cacheCmd.AddCommand(
+		infoCmd,
+		&cobra.Command{
+			Use:         "path",
+			Short:       "Prints the location of the cache",
+			Args:        cobra.NoArgs,
+			Run:         c.cachePathRun,
+			Annotations: map[string]string{readOnlyAnnotation: ""},
+		},
+		&cobra.Command{
+			Use:          "clean",
+			Short:        "Deletes the cache",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE:         c.cacheCleanRunE,
+		},
+		&cobra.Command{
+			Use:          "prune",
+			Short:        "Deletes unreachable objects from the cache",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE:         c.cachePruneRunE,
+		},
+		&cobra.Command{
+			Use:   "export <file>",
+			Short: "Exports the cache into a git bundle or a snapshot",
+			Long: `Exports the templates of the cache, of --ref or --commit-hash if given,
+into <file> for machines that cannot reach --source.
+A <file> ending with .bundle becomes a git bundle that contains the whole
+history, a <file> ending with .tar.gz or .tgz becomes a snapshot that
+contains only the files of the commit.`,
+			Args:         cobra.ExactArgs(1),
+			SilenceUsage: true,
+			RunE:         c.cacheExportRunE,
+		},
+		&cobra.Command{
+			Use:   "import <file>",
+			Short: "Initializes or refreshes the cache from a git bundle or a snapshot",
+			Long: `Initializes or refreshes the cache of --source from <file>, a git bundle
+or a snapshot created by gig cache export. A git bundle is added to
+the repository in the cache while a snapshot replaces the cache.
+A snapshot contains only a single commit and cannot be updated.`,
+			Args:         cobra.ExactArgs(1),
+			SilenceUsage: true,
+			RunE:         c.cacheImportRunE,
+		},
+	)
+
+	return cacheCmd
+}
+
+func (c *command) cachePathRun(cmd *cobra.Command, args []string) {
+	fmt.Fprintln(c.output, c.repoPath())
+}
+
+func (c *command) cacheInfoRunE(cmd *cobra.Command, args []string) error {
+	info, err := repo.Stat(c.repoPath(), c.repoOptions()...)
+	if errors.Is(err, repo.ErrNotCached) {
+		return errors.Errorf("cmd: no templates cached in %s", c.repoPath())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if c.cacheJSON {
+		enc := json.NewEncoder(c.output)
+		enc.SetIndent("", "  ")
+
+		return errors.Wrap(enc.Encode(info), "cmd: encode cache info")
+	}
+
+	lastFetch := "unknown"
+	if info.LastFetch != nil {
+		lastFetch = info.LastFetch.Local().Format(time.RFC3339)
+	}
+
+	fmt.Fprintf(c.output, "Path:       %s\n", info.Path)
+	fmt.Fprintf(c.output, "Size:       %s\n", formatSize(info.Size))
+	fmt.Fprintf(c.output, "Commit:     %s\n", info.Commit)
+	fmt.Fprintf(c.output, "Remote:     %s\n", info.Remote)
+
+	if info.Snapshot {
+		fmt.Fprintf(c.output, "Snapshot:   created at %s\n", lastFetch)
+
+		return nil
+	}
+
+	fmt.Fprintf(c.output, "Last fetch: %s\n", lastFetch)
+
+	commits := fmt.Sprintf("%d commits", info.Commits)
+	if info.Commits == 1 {
+		commits = "1 commit"
+	}
+
+	history := "complete, " + commits
+	if info.Shallow {
+		history = "shallow, " + commits + ", older ones are fetched when needed"
+	}
+
+	fmt.Fprintf(c.output, "History:    %s\n", history)
+
+	if info.Partial {
+		files := fmt.Sprintf("partial, %d files of the cached commits are left out until needed", info.Missing)
+		if info.Saved > 0 {
+			files += fmt.Sprintf(", saving about %s", formatSize(info.Saved))
+		}
+
+		fmt.Fprintf(c.output, "Files:      %s\n", files)
+	}
+
+	return nil
+}
+
+func (c *command) cacheCleanRunE(cmd *cobra.Command, args []string) error {
+	removed, err := repo.Clean(c.repoPath(), c.repoOptions()...)
+	for _, dir := range removed {
+		fmt.Fprintf(c.output, "Removed %s\n", dir)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(removed) == 0 {
+		fmt.Fprintf(c.output, "No templates cached in %s\n", c.repoPath())
+	}
+
+	return nil
+}
+
+func (c *command) cachePruneRunE(cmd *cobra.Command, args []string) error {
+	r, err := c.openCache()
+	if err != nil {
+		return err
+	}
+
+	before, err := repo.Size(c.repoPath())
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Prune(r, c.repoOptions()...); err != nil {
+		return err
+	}
+
+	after, err := repo.Size(c.repoPath())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output, "Pruned %s from %s to %s\n", c.repoPath(), formatSize(before), formatSize(after))
+
+	return nil
+}
+
+func (c *command) cacheExportRunE(cmd *cobra.Command, args []string) (err error) {
+	name := args[0]
+
+	var export func(w io.Writer, r *git.Repository, rev string) (string, error)
+
+	switch {
+	case strings.HasSuffix(name, ".bundle"):
+		export = func(w io.Writer, r *git.Repository, rev string) (string, error) {
+			if !c.offline {
+				if err := repo.Deepen(r, c.repoOptions()...); err != nil {
+					return "", err
+				}
+			}
+
+			return repo.ExportBundle(w, r, rev, c.repoOptions()...)
+		}
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		export = func(w io.Writer, r *git.Repository, rev string) (string, error) {
+			return repo.ExportSnapshot(w, r, rev, c.source, c.repoOptions()...)
+		}
+	default:
+		return errors.Errorf("cmd: %s must end with .bundle, .tar.gz, or .tgz", name)
+	}
+
+	rev := c.ref
+	if c.commitHash != "" {
+		if rev != "" {
+			return errors.New("cmd: --commit-hash and --ref cannot be used together")
+		}
+
+		rev = c.commitHash
+	}
+
+	r, err := c.openCache()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "cmd: create export file")
+	}
+
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = errors.Wrap(cerr, "cmd: write export file")
+		}
+
+		if err != nil {
+			os.Remove(name) //nolint:errcheck
+		}
+	}()
+
+	hash, err := export(f, r, rev)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output, "Exported commit hash %s to %s\n", hash, name)
+
+	return nil
+}
+
+func (c *command) cacheImportRunE(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return errors.Wrap(err, "cmd: open import file")
+	}
+
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil {
+		return errors.Wrap(err, "cmd: read import file")
+	}
+
+	if string(head) == gzipMagic {
+		s, err := repo.ImportSnapshot(c.repoPath(), br, c.repoOptions()...)
+		if err != nil {
+			return err
+		}
+
+		if s.Source != repo.RedactSource(c.source) {
+			fmt.Fprintf(os.Stderr, "Warning: the snapshot was created from %s, not from %s\n",
+				repo.DisplaySource(s.Source), repo.DisplaySource(c.source))
+		}
+
+		fmt.Fprintf(c.output, "Imported snapshot of commit hash %s into %s\n", s.Commit, c.repoPath())
+
+		return nil
+	}
+
+	head, _ = br.Peek(len("# v2 git bundle"))
+	if !repo.IsBundle(head) {
+		return errors.Errorf("cmd: %s is neither a git bundle nor a snapshot", args[0])
+	}
+
+	hash, err := repo.ImportBundle(c.repoPath(), c.source, br, c.repoOptions()...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output, "Imported commit hash %s into %s\n", hash, c.repoPath())
+
+	return nil
+}
+
+// gzipMagic starts every snapshot.
+const gzipMagic = "\x1f\x8b"
+
+// openCache opens the repository cached for --source without cloning it.
+func (c *command) openCache() (*git.Repository, error) {
+	r, err := repo.Open(c.repoPath())
+	if !errors.Is(err, repo.ErrNotCached) {
+		return r, err
+	}
+
+	if _, _, err := repo.OpenSnapshot(c.repoPath()); err == nil {
+		return nil, errors.Errorf("cmd: %s contains a snapshot instead of a repository", c.repoPath())
+	}
+
+	return nil, errors.Errorf("cmd: no templates cached in %s", c.repoPath())
+}
+
+// formatSize formats size in bytes with a binary unit, e.g. 1.5 MiB.
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
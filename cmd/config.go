@@ -0,0 +1,158 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenPeeDeeP/xdg"
+	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix is the prefix of the environment variables that set
+// the global flags, e.g. GIG_CACHE_PATH sets --cache-path.
+const envPrefix = "GIG_"
+
+// applyEnv sets the flags of fs from their environment variables.
+// It has to be called before the flags are parsed so that the flags
+// given in the command line take precedence.
+func applyEnv(fs *pflag.FlagSet) error {
+	var errs *multierror.Error
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		name := envName(f.Name)
+
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if err := setValue(f, v); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "cmd: invalid value of %s", name))
+		}
+	})
+
+	return errs.ErrorOrNil()
+}
+
+// setValue sets f to v. Unlike Set, it replaces the values of the flags
+// that can be repeated instead of appending to them, and leaves them
+// to be replaced by the next Set: the environment variables replace
+// the lists of the configuration file and the command line replaces both.
+func setValue(f *pflag.Flag, v string) error {
+	s, ok := f.Value.(pflag.SliceValue)
+	if !ok {
+		return f.Value.Set(v)
+	}
+
+	values := []string{}
+
+	if v != "" {
+		var err error
+
+		values, err = csv.NewReader(strings.NewReader(v)).Read()
+		if err != nil {
+			return errors.Wrap(err, "cmd: parse list")
+		}
+	}
+
+	return errors.Wrap(s.Replace(values), "cmd: replace list")
+}
+
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// configFile returns the location of the configuration file. Its keys are
+// the names of the global flags, e.g.
+//
+//	cache-path: /tmp/gig
+//	source: https://git.example.com/mirrors/gitignore.git
+func configFile() string {
+	return filepath.Join(xdg.ConfigHome(), "gig", "config.yaml")
+}
+
+// userTemplatesDir returns the directory of the custom templates of the user.
+func userTemplatesDir() string {
+	return filepath.Join(xdg.ConfigHome(), "gig", "templates")
+}
+
+// configValue formats v the way it is given in the command line.
+// Lists are used for the flags that can be repeated.
+func configValue(v interface{}) string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return fmt.Sprint(v)
+	}
+
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, fmt.Sprint(item))
+	}
+
+	return strings.Join(values, ",")
+}
+
+// applyConfig sets the flags of fs from the configuration file in path,
+// which does not have to exist. Like applyEnv, it has to be called before
+// the flags are parsed; it is called before applyEnv so that
+// the environment variables take precedence over the file.
+func applyConfig(fs *pflag.FlagSet, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "cmd: read config file")
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return errors.Wrapf(err, "cmd: parse config file %s", path)
+	}
+
+	var errs *multierror.Error
+
+	for key, v := range values {
+		f := fs.Lookup(key)
+		if f == nil {
+			errs = multierror.Append(errs, errors.Errorf("cmd: unknown key %s in config file %s", key, path))
+
+			continue
+		}
+
+		if err := setValue(f, configValue(v)); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "cmd: invalid value of %s in config file %s", key, path))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,146 @@
+package cmd_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Precedence(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	other := newSourceRepo(t)
+	other.commit(map[string]string{
+		"templates/Go.gitignore": "*.dll\n",
+		"templates/order":        "",
+	})
+
+	dirs := make(map[string]string)
+
+	for _, name := range []string{"config", "env", "cli", "cli2"} {
+		dirs[name] = tempDir(t)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dirs[name], "Go.patch"), []byte("/"+name+"/\n"), 0600))
+	}
+
+	for _, tc := range []struct {
+		name   string
+		env    map[string]string
+		args   []string
+		want   []string
+		source string
+	}{
+		{
+			name:   "config",
+			want:   []string{"/config/"},
+			source: "*.exe",
+		},
+		{
+			name: "env",
+			env: map[string]string{
+				"GIG_TEMPLATES_DIR": dirs["env"],
+				"GIG_SOURCE":        other.dir,
+			},
+			want:   []string{"/env/"},
+			source: "*.dll",
+		},
+		{
+			name: "cli",
+			env: map[string]string{
+				"GIG_TEMPLATES_DIR": dirs["env"],
+				"GIG_SOURCE":        other.dir,
+			},
+			args: []string{
+				"--templates-dir", dirs["cli"],
+				"--templates-dir", dirs["cli2"],
+				"--source", source.dir,
+			},
+			want:   []string{"/cli/", "/cli2/"},
+			source: "*.exe",
+		},
+		{
+			name:   "cli without env",
+			args:   []string{"--templates-dir", dirs["cli"]},
+			want:   []string{"/cli/"},
+			source: "*.exe",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			g := newGig(t)
+
+			config := "source: " + source.dir + "\ntemplates-dir:\n  - " + dirs["config"] + "\n"
+			path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "gig", "config.yaml")
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+			require.NoError(t, ioutil.WriteFile(path, []byte(config), 0600))
+
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			out, err := g.run(append(tc.args, "gen", "go")...)
+			require.NoError(t, err)
+			assert.Contains(t, out, tc.source)
+
+			for name := range dirs {
+				if contains(tc.want, "/"+name+"/") {
+					assert.Contains(t, out, "/"+name+"/\n")
+				} else {
+					assert.NotContains(t, out, "/"+name+"/\n")
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Invalid(t *testing.T) {
+	newGig(t)
+
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "gig", "config.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+
+	newCommand := func() error {
+		_, err := cmd.NewCommand(context.Background(), ioutil.Discard, nil)
+
+		return err
+	}
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("unknown: true\n"), 0600))
+
+	err := newCommand()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown key unknown in config file")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("offline: maybe\n"), 0600))
+
+	err = newCommand()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid value of offline in config file")
+
+	require.NoError(t, os.Remove(path))
+	t.Setenv("GIG_TIMEOUT", "soon")
+
+	err = newCommand()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid value of GIG_TIMEOUT")
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
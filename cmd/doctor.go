@@ -0,0 +1,94 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd(c *command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks the template cache and repairs it",
+		Long: `Checks the cache of the templates for problems,
+e.g. left behind by an interrupted clone, and replaces a broken cache
+with a fresh clone. With --offline the problems are only reported.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		// The cache might be broken, so it must not be opened beforehand.
+		PersistentPreRunE: c.sourceRunE,
+		RunE:              c.doctorRunE,
+	}
+}
+
+func (c *command) doctorRunE(cmd *cobra.Command, args []string) error {
+	problems, err := repo.Check(c.repoPath(), `.`)
+	if errors.Is(err, repo.ErrNotCached) {
+		fmt.Fprintf(c.output, "No templates cached in %s yet\n", c.repoPath())
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintf(c.output, "No problems found in %s\n", c.repoPath())
+
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(c.output, "Problem: %s\n", p)
+	}
+
+	if c.offline {
+		return errors.New("cmd: the cache has problems, run gig doctor without --offline to repair it")
+	}
+
+	aside, _, err := repo.Repair(c.repoPath(), c.source, c.repoOptions()...)
+	if err != nil {
+		return err
+	}
+
+	if aside != "" {
+		fmt.Fprintf(c.output, "Moved the broken cache to %s\n", aside)
+	}
+
+	problems, err = repo.Check(c.repoPath(), `.`)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("cmd: the cache still has problems after the repair: %v", problems)
+	}
+
+	fmt.Fprintf(c.output, "Repaired %s\n", c.repoPath())
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+// NewCommand returns the root command of gig for the tests, which writes to w
+// and uses the snapshot returned by openEmbedded as the embedded templates.
+func NewCommand(ctx context.Context, w io.Writer,
+	openEmbedded func() (*repo.Snapshot, fs.FS, error)) (*cobra.Command, error) {
+	rootCmd, c, err := newCommand(ctx, w, "test")
+	if err != nil {
+		return nil, err
+	}
+
+	c.openEmbedded = openEmbedded
+
+	return rootCmd, nil
+}
@@ -33,7 +33,7 @@ func newGenCmd(c *command) *cobra.Command {
 which should contain one or more valid names (case insensitive).
 Valid names can be obtained from the list subcommand.
 At the very first run the program will clone the templates repository
-https://github.com/toptal/gitignore.git into $XDG_CACHE_HOME/gig.`,
+--source into --cache-path.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: c.genRunE,
 	}
@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGen_Diff(t *testing.T) {
+	source := newSourceRepo(t)
+	hash := source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	url := "--source=file://" + filepath.ToSlash(source.dir)
+
+	diff := fmt.Sprintf(`--- .gitignore
++++ .gitignore (generated)
+@@ -1,2 +1,8 @@
+ /bin/
+ 
++# gig:begin templates=go commit=%s
++
++### Go ###
++*.exe
++# gig:end
++
+`, hash)
+
+	tests := []struct {
+		name    string
+		args    []string
+		written bool
+	}{
+		{name: "diff", args: []string{"--diff"}, written: false},
+		{name: "dry run", args: []string{"--dry-run"}, written: false},
+		{name: "dry run with file", args: []string{"--dry-run", "--file"}, written: false},
+		{name: "diff with file", args: []string{"--diff", "--file"}, written: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGig(t)
+			require.NoError(t, ioutil.WriteFile(".gitignore", []byte("/bin/\n"), 0600))
+
+			out, err := g.run(append(append([]string{url, "gen"}, tt.args...), "go")...)
+			require.NoError(t, err)
+			assert.Equal(t, diff, out)
+
+			if !tt.written {
+				assert.Equal(t, "/bin/\n", g.readFile(".gitignore"))
+				assert.NoFileExists(t, lockfile.Name)
+
+				return
+			}
+
+			assert.Contains(t, g.readFile(".gitignore"), "### Go ###\n*.exe\n")
+			assert.FileExists(t, lockfile.Name)
+
+			// Nothing is left to change.
+			out, err = g.run(url, "gen", "--dry-run", "go")
+			require.NoError(t, err)
+			assert.Empty(t, out)
+		})
+	}
+
+	t.Run("no file", func(t *testing.T) {
+		g := newGig(t)
+
+		_, err := g.run(url, "gen", "--dry-run", "go")
+		require.NoError(t, err)
+
+		assert.NoFileExists(t, ".gitignore")
+	})
+}
@@ -0,0 +1,168 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/shihanng/gig/cmd"
+	"github.com/shihanng/gig/internal/embedded"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceRepo is a local templates repository used as the source
+// of the tests that do not require internet access.
+type sourceRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+}
+
+func newSourceRepo(t *testing.T) *sourceRepo {
+	t.Helper()
+
+	dir := tempDir(t)
+
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	return &sourceRepo{t: t, dir: dir, repo: r}
+}
+
+// commit writes files (path to content) into the repository and commits them.
+func (s *sourceRepo) commit(files map[string]string) string {
+	s.t.Helper()
+
+	wt, err := s.repo.Worktree()
+	require.NoError(s.t, err)
+
+	for path, content := range files {
+		full := filepath.Join(s.dir, path)
+		require.NoError(s.t, os.MkdirAll(filepath.Dir(full), 0700))
+		require.NoError(s.t, ioutil.WriteFile(full, []byte(content), 0600))
+
+		_, err := wt.Add(path)
+		require.NoError(s.t, err)
+	}
+
+	hash, err := wt.Commit("update templates", &git.CommitOptions{
+		Author: &object.Signature{Name: "gig", Email: "gig@example.com", When: time.Now()},
+	})
+	require.NoError(s.t, err)
+
+	return hash.String()
+}
+
+// newTemplatesSource returns the --source flag of a local repository with the
+// templates Elm and Go, and the hash of its commit.
+func newTemplatesSource(t *testing.T) (string, string) {
+	t.Helper()
+
+	source := newSourceRepo(t)
+	hash := source.commit(map[string]string{
+		"templates/Elm.gitignore": "elm-stuff\n",
+		"templates/Go.gitignore":  "*.exe\n",
+		"templates/order":         "",
+	})
+
+	return "--source=" + source.dir, hash
+}
+
+// allowPartialClone lets the git backend clone the repository partially
+// through a file:// URL, which it returns.
+func (s *sourceRepo) allowPartialClone() string {
+	s.t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		s.t.Skip("git is not installed")
+	}
+
+	require.NoError(s.t, exec.Command("git", "-C", s.dir, "config", "uploadpack.allowFilter", "true").Run())
+	require.NoError(s.t, exec.Command("git", "-C", s.dir, "config", "uploadpack.allowAnySHA1InWant", "true").Run())
+
+	return "file://" + filepath.ToSlash(s.dir)
+}
+
+// gig runs the commands of gig in a project directory, the working directory
+// of the test, with their own cache and configuration directory.
+type gig struct {
+	t         *testing.T
+	dir       string
+	cachePath string
+
+	// embedded returns the templates embedded in gig, none by default.
+	embedded func() (*repo.Snapshot, fs.FS, error)
+}
+
+func newGig(t *testing.T) *gig {
+	t.Helper()
+
+	home := tempDir(t)
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	dir := tempDir(t)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+
+	t.Cleanup(func() { os.Chdir(wd) }) //nolint:errcheck
+
+	return &gig{
+		t:         t,
+		dir:       dir,
+		cachePath: filepath.Join(home, ".cache", "gig"),
+		embedded: func() (*repo.Snapshot, fs.FS, error) {
+			return nil, nil, embedded.ErrNotEmbedded
+		},
+	}
+}
+
+// run runs gig with args and returns what it printed.
+func (g *gig) run(args ...string) (string, error) {
+	g.t.Helper()
+
+	var out bytes.Buffer
+
+	rootCmd, err := cmd.NewCommand(context.Background(), &out, g.embedded)
+	require.NoError(g.t, err)
+
+	rootCmd.SetArgs(append([]string{"--cache-path", g.cachePath}, args...))
+	rootCmd.SetOut(ioutil.Discard)
+	rootCmd.SetErr(ioutil.Discard)
+
+	err = rootCmd.Execute()
+
+	return out.String(), err
+}
+
+// readFile returns the content of the file name of the project directory.
+func (g *gig) readFile(name string) string {
+	g.t.Helper()
+
+	content, err := ioutil.ReadFile(filepath.Join(g.dir, name))
+	require.NoError(g.t, err)
+
+	return string(content)
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
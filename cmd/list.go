@@ -22,6 +22,7 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/cockroachdb/errors"
@@ -33,19 +34,51 @@ func newListCmd(c *command) *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
 		Short: "List all supported templates",
-		Args:  cobra.NoArgs,
-		RunE:  c.listRunE,
+		Long: `List all supported templates.
+
+When custom templates or several sources are used, the templates
+are grouped by the directory or source they come from, in the order
+in which unqualified template names are looked up.
+The category of a template, e.g. Global for the templates in the Global
+directory of github.com/github/gitignore, is printed after a tab.`,
+		Args: cobra.NoArgs,
+		RunE: c.listRunE,
 	}
 }
 
 func (c *command) listRunE(cmd *cobra.Command, args []string) error {
-	templates, err := file.List(c.templatePath())
+	if len(c.layers) == 1 {
+		return c.listTemplates(c.layers[0], "")
+	}
+
+	for i, layer := range c.layers {
+		if i > 0 {
+			fmt.Fprintln(c.output)
+		}
+
+		fmt.Fprintf(c.output, "%s:\n", layer.Name)
+
+		if err := c.listTemplates(layer, "  "); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *command) listTemplates(layer file.Layer, indent string) error {
+	templates, err := file.List(layer.FS)
 	if err != nil {
 		return err
 	}
 
 	for _, t := range templates {
-		if _, err := io.WriteString(c.output, t+"\n"); err != nil {
+		line := indent + t
+		if category := layer.Categories[t]; category != "" {
+			line += "\t" + category
+		}
+
+		if _, err := io.WriteString(c.output, line+"\n"); err != nil {
 			return errors.Wrap(err, "cmd/list: outputing")
 		}
 	}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/file"
+	"github.com/spf13/cobra"
+)
+
+func newRemoveCmd(c *command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove [template name]",
+		Short: "Removes templates from .gitignore",
+		Long: `Removes the given [template name] from the templates already used
+in the .gitignore file of the current working directory and regenerates it.
+The templates in use are read from the managed block of the file or,
+if there is none, from the headers of a file previously generated by gig.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: c.removeRunE,
+	}
+}
+
+func (c *command) removeRunE(cmd *cobra.Command, args []string) error {
+	doc, items, err := readTemplates()
+	if err != nil {
+		return err
+	}
+
+	removed := make(map[string]bool, len(args))
+
+	for _, arg := range args {
+		removed[file.Canon(arg)] = false
+	}
+
+	kept := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if _, ok := removed[file.Canon(item)]; ok {
+			removed[file.Canon(item)] = true
+
+			continue
+		}
+
+		kept = append(kept, item)
+	}
+
+	for _, arg := range args {
+		if !removed[file.Canon(arg)] {
+			return errors.Errorf("cmd: %s is not used in %s", arg, ignoreFile)
+		}
+	}
+
+	return c.writeIgnoreFile(doc, c.sortItems(kept))
+}
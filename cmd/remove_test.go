@@ -0,0 +1,64 @@
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemove(t *testing.T) {
+	source, hash := newTemplatesSource(t)
+
+	g := newGig(t)
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte("/bin/\n"), 0600))
+
+	_, err := g.run(source, "gen", "--file", "go", "elm")
+	require.NoError(t, err)
+
+	_, err = g.run(source, "remove", "Go")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`/bin/
+
+# gig:begin templates=elm commit=%s
+
+### Elm ###
+elm-stuff
+# gig:end
+`, hash), g.readFile(".gitignore"))
+}
+
+func TestRemove_Legacy(t *testing.T) {
+	source, hash := newTemplatesSource(t)
+
+	g := newGig(t)
+	require.NoError(t, ioutil.WriteFile(".gitignore",
+		[]byte("\n### Go ###\n*.exe\n\n### Elm ###\nelm-stuff\n"), 0600))
+
+	_, err := g.run(source, "remove", "elm")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`# gig:begin templates=Go commit=%s
+
+### Go ###
+*.exe
+# gig:end
+`, hash), g.readFile(".gitignore"))
+}
+
+func TestRemove_NotUsed(t *testing.T) {
+	source, _ := newTemplatesSource(t)
+
+	g := newGig(t)
+
+	_, err := g.run(source, "gen", "--file", "go")
+	require.NoError(t, err)
+
+	content := g.readFile(".gitignore")
+
+	_, err = g.run(source, "remove", "elm")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "elm is not used in .gitignore")
+	assert.Equal(t, content, g.readFile(".gitignore"))
+}
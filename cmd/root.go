@@ -22,51 +22,174 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/OpenPeeDeeP/xdg"
 	"github.com/cockroachdb/errors"
-	"github.com/go-git/go-git/v5/utils/ioutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/hashicorp/go-multierror"
+	"github.com/shihanng/gig/internal/block"
+	"github.com/shihanng/gig/internal/embedded"
 	"github.com/shihanng/gig/internal/file"
-	"github.com/shihanng/gig/internal/order"
+	"github.com/shihanng/gig/internal/layout"
+	"github.com/shihanng/gig/internal/lockfile"
 	"github.com/shihanng/gig/internal/repo"
 	"github.com/spf13/cobra"
 )
 
+const (
+	ignoreFile          = ".gitignore"
+	projectTemplatesDir = ".gig/templates"
+	fileFlagUsage       = `if specified will write the result into a managed block of
+the .gitignore file in the current working directory,
+rules outside of the block are kept untouched`
+)
+
+// The exit codes of gig when it is stopped, the same as those of timeout(1)
+// and of shells for a process interrupted with Ctrl-C.
+const (
+	exitTimeout     = 124
+	exitInterrupted = 130
+)
+
 func Execute(w io.Writer, version string) {
+	// Canceling the clones and fetches on Ctrl-C lets them clean up after themselves.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// A second Ctrl-C kills gig right away when the clean up hangs.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	rootCmd, command, err := newCommand(ctx, w, version)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	err = rootCmd.Execute()
+	code := command.exitCode(err)
+
+	if command.cancel != nil {
+		command.cancel()
+	}
+
+	stop()
+
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// newCommand returns the root command of gig with all its subcommands and flags,
+// whose defaults are taken from the configuration file and the environment.
+func newCommand(ctx context.Context, w io.Writer, version string) (*cobra.Command, *command, error) {
 	command := &command{
+		ctx:        ctx,
 		output:     w,
-		cachePath:  filepath.Join(xdg.CacheHome(), `gig`),
+		cachePath:  defaultCachePath(),
 		version:    version,
 		searchTool: "fzf -m",
+
+		openEmbedded: embedded.Open,
 	}
 
 	rootCmd := newRootCmd(command)
 
 	rootCmd.PersistentFlags().StringVarP(&command.commitHash, "commit-hash", "c", "",
-		`use templates from a specific commit hash of
-github.com/toptal/gitignore`)
+		`use templates from a specific commit hash of --source`)
+
+	rootCmd.PersistentFlags().StringVarP(&command.ref, "ref", "", "",
+		`use templates from a branch, a tag, a full or abbreviated
+commit hash of --source, append @{date}
+for the templates as of that date, e.g. master@{2020-01-31}`)
+
+	rootCmd.PersistentFlags().StringVarP(&command.cachePath, "cache-path", "", defaultCachePath(),
+		`location where the content of --source will be cached in`)
+
+	rootCmd.PersistentFlags().DurationVarP(&command.refreshInterval, "refresh-interval", "", 0,
+		`fetch the latest templates before running when the cache
+was last fetched longer ago than the given duration, e.g. 24h,
+falls back to the cache when the fetch fails (0 never fetches)`)
+
+	rootCmd.PersistentFlags().BoolVarP(&command.offline, "offline", "", false,
+		`never access the network, only use the templates
+that are already cached or embedded in gig`)
+
+	rootCmd.PersistentFlags().DurationVarP(&command.lockTimeout, "lock-timeout", "", repo.DefaultLockTimeout,
+		`how long to wait for other gig processes using the same cache`)
+
+	rootCmd.PersistentFlags().StringVarP(&command.source, "source", "", repo.SourceRepo,
+		`repository to get the templates from, a URL or a local path,
+every source is cached in its own directory in --cache-path`)
+
+	rootCmd.PersistentFlags().StringVarP(&command.layout, "layout", "", layout.Auto,
+		`how the templates are organized in --source: toptal for a
+templates directory like github.com/toptal/gitignore, github
+for the directory tree of github.com/github/gitignore,
+or auto to detect it, the other sources are always detected`)
+
+	rootCmd.PersistentFlags().StringSliceVarP(&command.templatesDirs, "templates-dir", "", nil,
+		`directory of custom templates that take precedence over
+.gig/templates, the templates in the configuration directory,
+and the templates of --source, can be repeated`)
+
+	rootCmd.PersistentFlags().StringSliceVarP(&command.sourcesValues, "sources", "", nil,
+		`additional named sources of templates in the form name=url,
+their templates can be qualified with the name, e.g. corp/Go,
+can be repeated`)
+
+	rootCmd.PersistentFlags().StringSliceVarP(&command.sourceOrderNames, "source-order", "", nil,
+		`names of the sources in the order in which unqualified
+templates are looked up, --source is named upstream
+(default upstream followed by --sources)`)
+
+	rootCmd.PersistentFlags().StringVarP(&command.gitBackend, "git-backend", "", repo.AutoBackend,
+		`how the sources are cloned and fetched: go-git clones the whole
+history, git runs the git command, which clones partially and fetches
+the files of the commits when they are used, auto uses git when
+it is installed and go-git otherwise`)
+
+	rootCmd.PersistentFlags().BoolVarP(&command.shallow, "shallow", "", true,
+		`clone only the latest commit with the git backend, the history
+is fetched when an older commit is used`)
+
+	rootCmd.PersistentFlags().DurationVarP(&command.timeout, "timeout", "", 0,
+		`give up cloning and fetching the sources after the given duration,
+e.g. 1m, and exit with status 124 (0 never times out)`)
+
+	rootCmd.PersistentFlags().StringVarP(&command.trustedKeysFile, "trusted-keys", "", "",
+		`file with the armored OpenPGP public keys of the allowed signers,
+commits of the sources that are not signed by one of them are refused`)
+
+	if err := applyConfig(rootCmd.PersistentFlags(), configFile()); err != nil {
+		return nil, nil, err
+	}
 
-	rootCmd.PersistentFlags().StringVarP(&command.cachePath, "cache-path", "", filepath.Join(xdg.CacheHome(), `gig`),
-		`location where the content of github.com/toptal/gitignore
-will be cached in`)
+	if err := applyEnv(rootCmd.PersistentFlags()); err != nil {
+		return nil, nil, err
+	}
 
 	genCmd := newGenCmd(command)
-
-	genCmd.Flags().BoolVarP(&command.genIsFile, "file", "f", false,
-		"if specified will create .gitignore file in the current working directory")
+	addGenFlags(genCmd, command)
 
 	searchCmd := newSearchCmd(command)
-
-	searchCmd.Flags().BoolVarP(&command.genIsFile, "file", "f", false,
-		"if specified will create .gitignore file in the current working directory")
+	addGenFlags(searchCmd, command)
 
 	autogenCmd := newAutogenCmd(command)
-
-	autogenCmd.Flags().BoolVarP(&command.genIsFile, "file", "f", false,
-		"if specified will create .gitignore file in the current working directory")
+	addGenFlags(autogenCmd, command)
 
 	rootCmd.AddCommand(
 		newListCmd(command),
@@ -74,81 +197,565 @@ will be cached in`)
 		newVersionCmd(command),
 		searchCmd,
 		autogenCmd,
+		newAddCmd(command),
+		newRemoveCmd(command),
+		newSyncCmd(command),
+		newVerifyCmd(command),
+		newUpdateCmd(command),
+		newDoctorCmd(command),
+		newCacheCmd(command),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	return rootCmd, command, nil
+}
+
+// exitCode returns the exit status of gig for the error err of the command.
+func (c *command) exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(c.ctx.Err(), context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(c.ctx.Err(), context.Canceled):
+		return exitInterrupted
+	default:
+		return 1
 	}
 }
 
+func addGenFlags(cmd *cobra.Command, c *command) {
+	cmd.Flags().BoolVarP(&c.genIsFile, "file", "f", false,
+		fileFlagUsage)
+
+	cmd.Flags().BoolVarP(&c.genDiff, "diff", "", false,
+		`print the unified diff between the current .gitignore file
+and the content that would be written with --file`)
+
+	cmd.Flags().BoolVarP(&c.genDryRun, "dry-run", "", false,
+		`like --diff but never writes the .gitignore file`)
+}
+
 func newRootCmd(c *command) *cobra.Command {
 	return &cobra.Command{
 		Use:   "gig",
 		Short: "A tool that generates .gitignore",
 		Long: `gig is a command line tool to help you create useful .gitignore files
 for your project. It is inspired by gitignore.io and make use of
-the large collection of useful .gitignore templates of the web service.`,
+the large collection of useful .gitignore templates of the web service.
+
+The global flags can also be set with environment variables prefixed
+with ` + envPrefix + `, e.g. ` + envPrefix + `CACHE_PATH for --cache-path, or in the configuration
+file ` + configFile() + `, e.g. cache-path: /tmp/gig.
+Environment variables take precedence over the configuration file.`,
 		PersistentPreRunE: c.rootRunE,
 	}
 }
 
 type command struct {
+	ctx        context.Context
+	cancel     context.CancelFunc // cancels the --timeout
 	output     io.Writer
 	commitHash string
+	ref        string
 	cachePath  string
+	source     string
+	layout     string
 	version    string
 	searchTool string
 
+	// openEmbedded returns the templates embedded in gig, see embedded.Open.
+	openEmbedded func() (*repo.Snapshot, fs.FS, error)
+
+	refreshInterval time.Duration
+	offline         bool
+	lockTimeout     time.Duration
+	timeout         time.Duration
+	trustedKeysFile string
+	gitBackend      string
+	shallow         bool
+	trustedKeys     string
+	signer          string
+
 	genIsFile bool
+	genDiff   bool
+	genDryRun bool
+
+	cacheJSON bool
+
+	repo           *git.Repository
+	embedded       *repo.Snapshot
+	upstreamLayout layout.Layout
+	orders         map[string]int
+	layers         file.Layers
+	sourceLayers   file.Layers
+	templatesDirs  []string
+
+	sourcesValues    []string
+	sourceOrderNames []string
+	namedSources     []namedSource
+	sourceCommits    map[string]string
+	lock             *lockfile.Lock
 }
 
 func (c *command) rootRunE(cmd *cobra.Command, args []string) error {
-	r, err := repo.New(c.cachePath, repo.SourceRepo)
+	if err := c.sourceRunE(cmd, args); err != nil {
+		return err
+	}
+
+	rev := c.ref
+	if c.commitHash != "" {
+		if rev != "" {
+			return errors.New("cmd: --commit-hash and --ref cannot be used together")
+		}
+
+		rev = c.commitHash
+	}
+
+	err := c.prepare(rev)
+	if err == nil || c.offline || c.ctx.Err() != nil {
+		return err
+	}
+
+	// Only re-clone when the failure comes from a broken cache and not
+	// e.g. from an unknown --ref.
+	problems, checkErr := repo.Check(c.repoPath(), `.`)
+	if checkErr != nil || len(problems) == 0 {
+		return err
+	}
+
+	if err := c.repair(problems); err != nil {
+		return err
+	}
+
+	return c.prepare(rev)
+}
+
+// customLayers returns the directories of custom templates that take
+// precedence over the templates of --source: those given with --templates-dir,
+// the templates of the project, and the templates of the user.
+func (c *command) customLayers() (file.Layers, error) {
+	var layers file.Layers
+
+	for _, dir := range c.templatesDirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, errors.Errorf("cmd: templates directory %s does not exist", dir)
+		}
+
+		layers = append(layers, file.Layer{Name: dir, FS: os.DirFS(dir)})
+	}
+
+	for _, dir := range []string{projectTemplatesDir, userTemplatesDir()} {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			layers = append(layers, file.Layer{Name: dir, FS: os.DirFS(dir)})
+		}
+	}
+
+	return layers, nil
+}
+
+// readOnlyAnnotation marks the commands that only report on the cache
+// and never change it, not even to migrate it.
+const readOnlyAnnotation = "gig_read_only"
+
+// defaultCachePath returns the default of --cache-path.
+func defaultCachePath() string {
+	return filepath.Join(xdg.CacheHome(), `gig`)
+}
+
+// sourceRunE normalizes --source and moves a cache of --source of an older
+// version of gig into the directory of its source. It is the only preparation
+// of the commands that manage the cache themselves.
+func (c *command) sourceRunE(cmd *cobra.Command, args []string) error {
+	if c.timeout > 0 && c.cancel == nil {
+		c.ctx, c.cancel = context.WithTimeout(c.ctx, c.timeout)
+	}
+
+	source, err := repo.NormalizeSource(c.source)
 	if err != nil {
 		return err
 	}
 
-	ch, err := repo.Checkout(r, c.commitHash)
+	c.source = source
+
+	if err := repo.CheckBackend(c.gitBackend); err != nil {
+		return err
+	}
+
+	if c.trustedKeysFile != "" {
+		keys, err := ioutil.ReadFile(c.trustedKeysFile)
+		if err != nil {
+			return errors.Wrap(err, "cmd: read trusted keys")
+		}
+
+		c.trustedKeys = string(keys)
+	}
+
+	c.namedSources, err = parseSources(c.sourcesValues)
+	if err != nil {
+		return err
+	}
+
+	// Only the default cache was used by older versions, any other
+	// --cache-path might be a repository of the user.
+	if _, ok := cmd.Annotations[readOnlyAnnotation]; ok || c.cachePath != defaultCachePath() {
+		return nil
+	}
+
+	return repo.MigrateCache(c.cachePath, c.source, c.repoOptions()...)
+}
+
+// repoPath returns the directory where the templates of --source are cached.
+func (c *command) repoPath() string {
+	return repo.CachePath(c.cachePath, c.source)
+}
+
+// prepare opens the cache and makes the templates of rev available.
+func (c *command) prepare(rev string) error {
+	reads, err := layout.Reads(c.layout)
 	if err != nil {
 		return err
 	}
 
+	r, ch, source, err := c.checkout(c.repoPath(), c.source, rev,
+		repo.WithVerified(func(signer string) { c.signer = signer }), repo.WithSparse(reads))
+	if err != nil {
+		ch, source, err = c.checkoutEmbedded(rev, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	l, err := layout.Get(c.layout, source)
+	if err != nil {
+		return err
+	}
+
+	templates, categories, err := l.Templates(source)
+	if err != nil {
+		return err
+	}
+
+	orders, err := l.Order(templates)
+	if err != nil {
+		return err
+	}
+
+	layers, err := c.customLayers()
+	if err != nil {
+		return err
+	}
+
+	sources, err := c.prepareSources()
+	if err != nil {
+		return err
+	}
+
+	sources[upstreamSource] = file.Layer{
+		Name:       upstreamSource,
+		FS:         templates,
+		Categories: categories,
+		Source:     true,
+	}
+
+	order, err := c.sourceOrder()
+	if err != nil {
+		return err
+	}
+
+	sourceLayers := make(file.Layers, 0, len(order))
+	for _, name := range order {
+		sourceLayers = append(sourceLayers, sources[name])
+	}
+
+	c.orders = orders
+	c.layers = append(layers, sourceLayers...)
+	c.sourceLayers = sourceLayers
+	c.upstreamLayout = l
+
+	c.repo = r
 	c.commitHash = ch
 
 	return nil
 }
 
-func (c *command) generateIgnoreFile(items []string) error {
-	orders, err := order.ReadOrder(filepath.Join(c.templatePath(), `order`))
+// repair reports the problems found in the cache and replaces it with a fresh clone.
+func (c *command) repair(problems []string) error {
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "Cache problem: %s\n", p)
+	}
+
+	aside, _, err := repo.Repair(c.repoPath(), c.source, c.repoOptions()...)
 	if err != nil {
 		return err
 	}
 
-	items = file.Sort(items, orders)
+	if aside != "" {
+		fmt.Fprintf(os.Stderr, "Moved the broken cache to %s and cloned it again\n", aside)
+	}
+
+	return nil
+}
+
+func (c *command) repoOptions() []repo.Option {
+	return []repo.Option{
+		repo.WithContext(c.ctx),
+		repo.WithLockTimeout(c.lockTimeout),
+		repo.WithLockWait(func(lockPath string) {
+			fmt.Fprintf(os.Stderr, "Waiting for lock %s held by another process...\n", lockPath)
+		}),
+		sourceCredentials(),
+		repo.WithTrustedKeys(c.trustedKeys),
+		repo.WithBackend(c.gitBackend),
+		repo.WithShallow(c.shallow),
+		repo.WithOffline(c.offline),
+	}
+}
+
+// sourceCredentials returns the credentials for --source given in
+// the environment. A token is used as the password, with "oauth2"
+// as the username unless one is given.
+func sourceCredentials() repo.Option {
+	username := os.Getenv(envPrefix + "SOURCE_USERNAME")
+	password := os.Getenv(envPrefix + "SOURCE_PASSWORD")
+
+	if token := os.Getenv(envPrefix + "SOURCE_TOKEN"); token != "" {
+		password = token
+
+		if username == "" {
+			username = "oauth2"
+		}
+	}
+
+	return repo.WithCredentials(username, password)
+}
+
+// checkout returns the commit hash and the files of rev of source cached in path.
+// The cache is either a repository, which is returned, or a snapshot, which only
+// has the files of a single commit. opts are added to those of the checkout.
+func (c *command) checkout(path, source, rev string, opts ...repo.Option) (*git.Repository, string, fs.FS, error) {
+	if s, fsys, err := repo.OpenSnapshot(path); !errors.Is(err, repo.ErrNotCached) {
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if c.trustedKeys != "" {
+			return nil, "", nil, errors.Errorf("cmd: %s contains a snapshot whose signature cannot be verified", path)
+		}
+
+		if rev != "" && !strings.HasPrefix(s.Commit, rev) {
+			return nil, "", nil, errors.Errorf("cmd: %s only contains a snapshot of commit %s, %s is not available", path, s.Commit, rev)
+		}
+
+		return nil, s.Commit, fsys, nil
+	}
+
+	r, err := c.openRepo(path, source)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	c.refresh(r)
+
+	opts = append(c.repoOptions(), opts...)
+
+	ch, fsys, err := repo.Checkout(r, rev, opts...)
+	if !errors.Is(err, repo.ErrUnknownRevision) || !repo.IsShallow(r) {
+		return r, ch, fsys, err
+	}
+
+	if c.offline {
+		return nil, "", nil, errors.Wrap(err, "cmd: the cache is shallow, run without --offline to fetch older commits")
+	}
+
+	// rev might be older than the commits of the shallow clone.
+	if err := repo.Deepen(r, c.repoOptions()...); err != nil {
+		return nil, "", nil, err
+	}
+
+	ch, fsys, err = repo.Checkout(r, rev, opts...)
+
+	return r, ch, fsys, err
+}
+
+// checkoutEmbedded returns the commit hash and the files of the templates
+// embedded in gig when the templates of --source are not cached, e.g.
+// in offline mode or when the clone failed with cause. It returns cause
+// when the embedded templates cannot be used instead.
+func (c *command) checkoutEmbedded(rev string, cause error) (string, fs.FS, error) {
+	if c.source != repo.SourceRepo || c.trustedKeys != "" || c.ctx.Err() != nil || c.cached() {
+		return "", nil, cause
+	}
+
+	s, fsys, err := c.openEmbedded()
+	if err != nil {
+		return "", nil, cause
+	}
+
+	if rev != "" && !strings.HasPrefix(s.Commit, rev) {
+		return "", nil, cause
+	}
+
+	if !c.offline {
+		fmt.Fprintf(os.Stderr, "Warning: using the templates embedded in gig: %v\n", cause)
+	}
+
+	c.embedded = s
+
+	return s.Commit, fsys, nil
+}
+
+// cached reports whether there is a repository or a snapshot in the cache of --source.
+func (c *command) cached() bool {
+	if _, err := repo.Open(c.repoPath()); !errors.Is(err, repo.ErrNotCached) {
+		return true
+	}
+
+	_, _, err := repo.OpenSnapshot(c.repoPath())
+
+	return !errors.Is(err, repo.ErrNotCached)
+}
+
+// openRepo opens the cache of source in path, cloning it unless in offline mode.
+func (c *command) openRepo(path, source string) (*git.Repository, error) {
+	if !c.offline {
+		return repo.New(path, source, c.repoOptions()...)
+	}
+
+	r, err := repo.Open(path)
+	if errors.Is(err, repo.ErrNotCached) {
+		return nil, errors.Errorf("cmd: no templates cached in %s, run gig once without --offline first", path)
+	}
 
-	wc, err := c.newWriteCloser()
+	return r, err
+}
+
+// refresh fetches the latest templates when the cache is older than
+// the refresh interval. Failures are ignored so that the cache can
+// still be used when the remote is unreachable.
+func (c *command) refresh(r *git.Repository) {
+	if c.offline || c.refreshInterval <= 0 {
+		return
+	}
+
+	lastFetch, err := repo.LastFetch(r)
+	if err == nil && time.Since(lastFetch) < c.refreshInterval {
+		return
+	}
+
+	_, _, _ = repo.Update(r, c.repoOptions()...)
+}
+
+func (c *command) generateIgnoreFile(items []string) error {
+	items = c.sortItems(items)
+
+	if !c.genIsFile && !c.genDiff && !c.genDryRun {
+		return c.layers.Generate(c.output, items...)
+	}
+
+	doc, err := readIgnoreFile()
 	if err != nil {
 		return err
 	}
 
-	defer wc.Close()
+	return c.writeIgnoreFile(doc, items)
+}
 
-	return file.Generate(wc, c.templatePath(), items...)
+func (c *command) sortItems(items []string) []string {
+	return file.Sort(items, c.orders)
 }
 
-func (c *command) newWriteCloser() (io.WriteCloser, error) {
-	if c.genIsFile {
-		f, err := os.Create(".gitignore")
-		if err != nil {
-			return nil, errors.Wrap(err, "cmd: create new file")
+// writeIgnoreFile replaces the managed block of doc with the content generated
+// from items and writes the result to the .gitignore file in the current working directory.
+// With --diff or --dry-run the changes to the file are printed first.
+func (c *command) writeIgnoreFile(doc *block.Document, items []string) error {
+	var body bytes.Buffer
+
+	var errs *multierror.Error
+
+	if err := c.layers.Generate(&body, items...); err != nil {
+		var merr *multierror.Error
+		if !errors.As(err, &merr) {
+			return err
 		}
 
-		return f, nil
+		errs = multierror.Append(errs, err)
 	}
 
-	return ioutil.WriteNopCloser(c.output), nil
+	content := doc.Render(block.Block{Templates: items, Commit: c.commitHash}, body.Bytes())
+
+	if c.genDiff || c.genDryRun {
+		current, err := ioutil.ReadFile(ignoreFile)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "cmd: read file")
+		}
+
+		if err := writeDiff(c.output, current, content); err != nil {
+			return err
+		}
+	}
+
+	// Without --file, --diff only shows what would be written.
+	if c.genDryRun || (c.genDiff && !c.genIsFile) {
+		return errs.ErrorOrNil()
+	}
+
+	if err := ioutil.WriteFile(ignoreFile, content, 0644); err != nil { //nolint:gosec,gomnd
+		return multierror.Append(errs, errors.Wrap(err, "cmd: write file"))
+	}
+
+	l := &lockfile.Lock{Templates: items, Commit: c.commitHash}
+	if len(c.sourceCommits) > 0 {
+		l.Sources = c.sourceCommits
+	}
+
+	checksums, err := c.sourceChecksums(items)
+	if err != nil {
+		return multierror.Append(errs, err)
+	}
+
+	if len(checksums) > 0 {
+		l.Checksums = checksums
+	}
+
+	if err := lockfile.Write(lockfile.Name, l); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return errs.ErrorOrNil()
 }
 
-func (c *command) templatePath() string {
-	return filepath.Join(c.cachePath, `templates`)
+func readIgnoreFile() (*block.Document, error) {
+	content, err := ioutil.ReadFile(ignoreFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "cmd: read file")
+	}
+
+	return block.Parse(content)
+}
+
+// readTemplates returns the templates used to generate the .gitignore file
+// in the current working directory. They are taken from the managed block or,
+// for a file generated without one, from the headers of the templates.
+// In the latter case the returned document is empty because
+// the whole file is considered to be generated.
+func readTemplates() (*block.Document, []string, error) {
+	doc, err := readIgnoreFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if doc.Block != nil {
+		return doc, doc.Block.Templates, nil
+	}
+
+	templates, err := file.Templates(bytes.NewReader(doc.Before))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(templates) == 0 {
+		return doc, nil, nil
+	}
+
+	return &block.Document{}, templates, nil
 }
@@ -0,0 +1,142 @@
+package cmd_test
+
+import (
+	"bytes"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffline_Embedded(t *testing.T) {
+	g := newGig(t)
+
+	var snapshot bytes.Buffer
+
+	require.NoError(t, repo.WriteSnapshot(&snapshot, &repo.Snapshot{
+		Commit:  "85ec4e7c71f2fdde1e2bd148d7f36d6458da12cc",
+		Source:  repo.SourceRepo,
+		Created: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, fstest.MapFS{
+		"templates/Go.gitignore": {Data: []byte("*.exe\n")},
+		"templates/order":        {Data: []byte("")},
+	}, "templates"))
+
+	g.embedded = func() (*repo.Snapshot, fs.FS, error) {
+		return repo.ReadSnapshot(bytes.NewReader(snapshot.Bytes()))
+	}
+
+	out, err := g.run("--offline", "gen", "go")
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.exe\n", out)
+
+	out, err = g.run("--offline", "version")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Using github.com/toptal/gitignore commit hash: 85ec4e7c71f2fdde1e2bd148d7f36d6458da12cc\n")
+	assert.Contains(t, out, "Templates snapshot embedded in gig")
+	assert.NotContains(t, out, "No templates snapshot embedded in gig")
+
+	// Nothing is cached from the embedded templates.
+	_, err = repo.Stat(repo.CachePath(g.cachePath, repo.SourceRepo))
+	assert.ErrorIs(t, err, repo.ErrNotCached)
+
+	// The embedded templates only stand in for the default source.
+	source := newSourceRepo(t)
+
+	_, err = g.run("--offline", "--source", source.dir, "gen", "go")
+	assert.Error(t, err)
+}
+
+func TestOffline_PartialClone(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	url := "--source=" + source.allowPartialClone()
+
+	g := newGig(t)
+
+	_, err := g.run(url, "--git-backend=git", "gen", "go")
+	require.NoError(t, err)
+
+	source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	_, err = g.run(url, "--git-backend=git", "update")
+	require.NoError(t, err)
+
+	trace := filepath.Join(g.dir, "trace")
+	t.Setenv("GIT_TRACE", trace)
+
+	// The files of the updated commit are not fetched offline.
+	_, err = g.run(url, "--git-backend=git", "--offline", "gen", "go")
+	assert.ErrorIs(t, err, repo.ErrOffline)
+
+	content, err := ioutil.ReadFile(trace)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "fetch")
+	assert.NotContains(t, string(content), "upload-pack")
+
+	out, err := g.run(url, "--git-backend=git", "gen", "go")
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.test\n", out)
+
+	out, err = g.run(url, "--git-backend=git", "--offline", "gen", "go")
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.test\n", out)
+}
+
+func TestRefresh(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	g := newGig(t)
+	args := []string{"--source", source.dir, "--refresh-interval", "24h", "gen", "go"}
+
+	out, err := g.run(args...)
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.exe\n", out)
+
+	source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	// The cache was fetched less than --refresh-interval ago.
+	out, err = g.run(args...)
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.exe\n", out)
+
+	// The bare repository of the cache records the time of the last fetch.
+	lastFetch := filepath.Join(repo.CachePath(g.cachePath, source.dir), "gig_last_fetch")
+	require.FileExists(t, lastFetch)
+
+	setLastFetch := func(at time.Time) {
+		t.Helper()
+		require.NoError(t, ioutil.WriteFile(lastFetch, []byte(at.UTC().Format(time.RFC3339)+"\n"), 0600))
+	}
+
+	setLastFetch(time.Now().Add(-48 * time.Hour))
+
+	out, err = g.run(args...)
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.test\n", out)
+
+	// An unreachable source falls back to the cache silently.
+	require.NoError(t, os.Rename(source.dir, source.dir+".moved"))
+	t.Cleanup(func() { os.RemoveAll(source.dir + ".moved") })
+
+	setLastFetch(time.Now().Add(-48 * time.Hour))
+
+	out, err = g.run(args...)
+	require.NoError(t, err)
+	assert.Equal(t, "\n### Go ###\n*.test\n", out)
+}
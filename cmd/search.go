@@ -30,7 +30,6 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/errors"
-	"github.com/shihanng/gig/internal/file"
 	"github.com/spf13/cobra"
 )
 
@@ -43,7 +42,7 @@ func newSearchCmd(c *command) *cobra.Command {
 This subcommand depends on fzf (https://github.com/junegunn/fzf)
 for the search functionality.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			templates, err := file.List(c.templatePath())
+			templates, err := c.layers.List()
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/file"
+	"github.com/shihanng/gig/internal/layout"
+	"github.com/shihanng/gig/internal/repo"
+)
+
+// upstreamSource is the name of the source given with --source.
+const upstreamSource = "upstream"
+
+// namedSource is an additional templates repository given with --sources.
+type namedSource struct {
+	name string
+	url  string
+}
+
+// parseSources parses the name=url values of --sources.
+func parseSources(values []string) ([]namedSource, error) {
+	sources := make([]namedSource, 0, len(values))
+	seen := map[string]bool{upstreamSource: true}
+
+	for _, v := range values {
+		i := strings.Index(v, "=")
+		if i <= 0 || i == len(v)-1 {
+			return nil, errors.Errorf("cmd: source %s is not of the form name=url", v)
+		}
+
+		name := v[:i]
+		if strings.Contains(name, "/") {
+			return nil, errors.Errorf("cmd: source name %s must not contain /", name)
+		}
+
+		if seen[name] {
+			return nil, errors.Errorf("cmd: source %s is defined more than once", name)
+		}
+
+		seen[name] = true
+
+		url, err := repo.NormalizeSource(v[i+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, namedSource{name: name, url: url})
+	}
+
+	return sources, nil
+}
+
+// sourceOrder returns the names of all sources in the order of their precedence.
+// The sources not given with --source-order follow in their default order:
+// upstream first, then the ones of --sources in the order they were given.
+func (c *command) sourceOrder() ([]string, error) {
+	known := map[string]bool{upstreamSource: true}
+	all := []string{upstreamSource}
+
+	for _, s := range c.namedSources {
+		known[s.name] = true
+		all = append(all, s.name)
+	}
+
+	order := make([]string, 0, len(all))
+	added := make(map[string]bool, len(all))
+
+	for _, name := range append(append([]string{}, c.sourceOrderNames...), all...) {
+		if !known[name] {
+			return nil, errors.Errorf("cmd: unknown source %s in --source-order", name)
+		}
+
+		if !added[name] {
+			order = append(order, name)
+			added[name] = true
+		}
+	}
+
+	return order, nil
+}
+
+// prepareSources makes the templates of every named source available as a layer.
+// The sources are pinned to the commit hashes of the lockfile, if any.
+func (c *command) prepareSources() (map[string]file.Layer, error) {
+	reads, err := layout.Reads(layout.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make(map[string]file.Layer, len(c.namedSources))
+	c.sourceCommits = make(map[string]string, len(c.namedSources))
+
+	for _, s := range c.namedSources {
+		var rev string
+		if c.lock != nil {
+			rev = c.lock.Sources[s.name]
+		}
+
+		_, ch, source, err := c.checkout(repo.CachePath(c.cachePath, s.url), s.url, rev, repo.WithSparse(reads))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cmd: source %s", s.name)
+		}
+
+		templates, categories, err := layout.Detect(source).Templates(source)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cmd: source %s", s.name)
+		}
+
+		layers[s.name] = file.Layer{Name: s.name, FS: templates, Categories: categories, Source: true}
+		c.sourceCommits[s.name] = ch
+	}
+
+	return layers, nil
+}
+
+// sourceChecksums returns the checksums of the template files of the sources
+// that are used for items, see file.Layers.Checksums. The custom templates
+// are left out because their directories differ from machine to machine,
+// and so are the overrides of the custom templates: the checksums
+// of a source are recorded whether its templates are overridden or not.
+func (c *command) sourceChecksums(items []string) (map[string]string, error) {
+	return c.sourceLayers.Checksums(items...)
+}
@@ -0,0 +1,169 @@
+package cmd_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSources(t *testing.T) {
+	upstream := newSourceRepo(t)
+	upstream.commit(map[string]string{
+		"templates/Go.gitignore":        "*.exe\n",
+		"templates/Go.patch":            "*.test\n",
+		"templates/Terraform.gitignore": ".terraform/\n",
+		"templates/order":               "",
+	})
+
+	corp := newSourceRepo(t)
+	corpCommit := corp.commit(map[string]string{
+		"templates/Go.gitignore": "*.dll\n",
+		"templates/Go.patch":     "*.out\n",
+	})
+
+	g := newGig(t)
+	args := []string{
+		"--source=file://" + filepath.ToSlash(upstream.dir),
+		"--sources=corp=" + corp.dir,
+	}
+
+	run := func(extra ...string) string {
+		t.Helper()
+
+		out, err := g.run(append(append([]string{}, args...), extra...)...)
+		require.NoError(t, err)
+
+		return out
+	}
+
+	// Unqualified names resolve to upstream, the first source by default,
+	// without the patches of corp.
+	assert.Equal(t, `
+### Go ###
+*.exe
+
+### Go Patch ###
+*.test
+`, run("gen", "go"))
+
+	// Qualified names use the template of that source.
+	assert.Equal(t, `
+### Go ###
+*.dll
+
+### Go Patch ###
+*.out
+
+### Terraform ###
+.terraform/
+`, run("gen", "corp/go", "upstream/terraform"))
+
+	// --source-order changes the resolution of unqualified names,
+	// templates missing in corp still come from upstream.
+	assert.Equal(t, `
+### Go ###
+*.dll
+
+### Go Patch ###
+*.out
+
+### Terraform ###
+.terraform/
+`, run("--source-order=corp", "gen", "go", "terraform"))
+
+	// The lockfile only records the files of the chosen source.
+	run("--source-order=corp", "gen", "--file", "go")
+
+	l, err := lockfile.Read(lockfile.Name)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"corp": corpCommit}, l.Sources)
+	assert.Contains(t, l.Checksums, "corp/Go.gitignore")
+	assert.Contains(t, l.Checksums, "corp/Go.patch")
+	assert.NotContains(t, l.Checksums, "upstream/Go.gitignore")
+	assert.NotContains(t, l.Checksums, "upstream/Go.patch")
+}
+
+func TestSources_Mirror(t *testing.T) {
+	files := map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/Go.patch":     "*.test\n",
+		"templates/order":        "",
+	}
+
+	upstream := newSourceRepo(t)
+	upstream.commit(files)
+
+	mirror := newSourceRepo(t)
+	mirror.commit(files)
+
+	g := newGig(t)
+
+	// The patches of a mirror are not written a second time.
+	out, err := g.run("--source="+upstream.dir, "--sources=mirror="+mirror.dir, "gen", "go")
+	require.NoError(t, err)
+	assert.Equal(t, `
+### Go ###
+*.exe
+
+### Go Patch ###
+*.test
+`, out)
+}
+
+func TestSources_Invalid(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "not name=url",
+			args: []string{"--sources=corp"},
+			err:  "source corp is not of the form name=url",
+		},
+		{
+			name: "empty url",
+			args: []string{"--sources=corp="},
+			err:  "source corp= is not of the form name=url",
+		},
+		{
+			name: "slash in name",
+			args: []string{"--sources=corp/data=" + source.dir},
+			err:  "source name corp/data must not contain /",
+		},
+		{
+			name: "duplicate",
+			args: []string{"--sources=corp=" + source.dir, "--sources=corp=" + source.dir},
+			err:  "source corp is defined more than once",
+		},
+		{
+			name: "upstream",
+			args: []string{"--sources=upstream=" + source.dir},
+			err:  "source upstream is defined more than once",
+		},
+		{
+			name: "unknown in order",
+			args: []string{"--sources=corp=" + source.dir, "--source-order=corp,team"},
+			err:  "unknown source team in --source-order",
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			g := newGig(t)
+
+			_, err := g.run(append(append([]string{"--source=" + source.dir}, tc.args...), "gen", "go")...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
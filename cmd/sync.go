@@ -0,0 +1,75 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/spf13/cobra"
+)
+
+func newSyncCmd(c *command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Regenerates .gitignore from the lockfile",
+		Long: `Regenerates the managed block of the .gitignore file in the current
+working directory from the templates and the commit hash recorded
+in the ` + lockfile.Name + ` lockfile. The lockfile is written every time
+gig writes .gitignore, commit it to reproduce the same .gitignore anywhere.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: c.lockRunE,
+		RunE:              c.syncRunE,
+	}
+}
+
+// lockRunE pins the templates repository to the commit hash of the lockfile
+// before preparing the repository as usual. It fails when the content of
+// the templates does not match the checksums of the lockfile.
+func (c *command) lockRunE(cmd *cobra.Command, args []string) error {
+	l, err := lockfile.Read(lockfile.Name)
+	if err != nil {
+		return err
+	}
+
+	c.lock = l
+	c.commitHash = l.Commit
+	c.ref = ""
+
+	if err := c.rootRunE(cmd, args); err != nil {
+		return err
+	}
+
+	checksums, err := c.sourceChecksums(l.Templates)
+	if err != nil {
+		return err
+	}
+
+	return l.VerifyChecksums(checksums)
+}
+
+func (c *command) syncRunE(cmd *cobra.Command, args []string) error {
+	doc, err := readIgnoreFile()
+	if err != nil {
+		return err
+	}
+
+	return c.writeIgnoreFile(doc, c.lock.Templates)
+}
@@ -0,0 +1,69 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync(t *testing.T) {
+	source := newSourceRepo(t)
+	first := source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	g := newGig(t)
+	url := "--source=file://" + filepath.ToSlash(source.dir)
+
+	_, err := g.run(url, "gen", "--file", "go")
+	require.NoError(t, err)
+
+	ignoreFile := g.readFile(".gitignore")
+	assert.Contains(t, ignoreFile, "*.exe\n")
+
+	l, err := lockfile.Read(lockfile.Name)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, l.Templates)
+	assert.Equal(t, first, l.Commit)
+	assert.Contains(t, l.Checksums, "upstream/Go.gitignore")
+
+	// The lockfile pins the templates to the first commit.
+	source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	_, err = g.run(url, "update")
+	require.NoError(t, err)
+
+	out, err := g.run(url, "gen", "go")
+	require.NoError(t, err)
+	assert.Contains(t, out, "*.test\n", "without the lockfile the latest commit is used")
+
+	require.NoError(t, os.Remove(".gitignore"))
+
+	_, err = g.run(url, "sync")
+	require.NoError(t, err)
+	assert.Equal(t, ignoreFile, g.readFile(".gitignore"))
+
+	l, err = lockfile.Read(lockfile.Name)
+	require.NoError(t, err)
+	assert.Equal(t, first, l.Commit)
+
+	// A template whose content does not match the lockfile is refused.
+	l.Checksums["upstream/Go.gitignore"] = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	content, err := json.Marshal(l)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(lockfile.Name, content, 0600))
+	require.NoError(t, os.Remove(".gitignore"))
+
+	_, err = g.run(url, "sync")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream/Go.gitignore changed")
+	assert.NoFileExists(t, ".gitignore")
+}
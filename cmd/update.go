@@ -0,0 +1,86 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+func newUpdateCmd(c *command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "update",
+		Short: "Fetches the latest templates",
+		Long: `Fetches the latest commits of --source into the cache
+and reports the templates that changed.`,
+		Args: cobra.NoArgs,
+		RunE: c.updateRunE,
+	}
+}
+
+func (c *command) updateRunE(cmd *cobra.Command, args []string) error {
+	if c.offline {
+		return errors.New("cmd: update is not available with --offline")
+	}
+
+	if c.embedded != nil {
+		return errors.Errorf("cmd: the templates could not be cloned into %s", c.repoPath())
+	}
+
+	if c.repo == nil {
+		return errors.New("cmd: the cache contains a snapshot that cannot be updated, import a newer one with gig cache import")
+	}
+
+	old, current, err := repo.Update(c.repo, c.repoOptions()...)
+	if err != nil {
+		return err
+	}
+
+	c.commitHash = current
+
+	if old == current {
+		fmt.Fprintf(c.output, "Already up to date at commit hash: %s\n", current)
+
+		return nil
+	}
+
+	fmt.Fprintf(c.output, "Updated from commit hash %s to %s\n", old, current)
+
+	changes, err := repo.Diff(c.repo, old, current)
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range changes {
+		name, ok := c.upstreamLayout.Template(ch.Path)
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(c.output, "  %-8s %s\n", ch.Action, name)
+	}
+
+	return nil
+}
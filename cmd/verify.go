@@ -0,0 +1,124 @@
+/*
+Copyright © 2019 Shi Han NG <shihanng@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/shihanng/gig/internal/block"
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCmd(c *command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verifies that .gitignore is up to date",
+		Long: `Verifies that the .gitignore file in the current working directory
+matches the content generated from the templates and commit hash recorded
+in the ` + lockfile.Name + ` lockfile or, if there is no lockfile,
+in the managed block of .gitignore.
+
+Exits with non-zero status and prints the difference when they do not match.
+Useful in CI to catch hand-edited managed blocks or a stale .gitignore.`,
+		Args:              cobra.NoArgs,
+		SilenceUsage:      true,
+		PersistentPreRunE: c.verifyPreRunE,
+		RunE:              c.verifyRunE,
+	}
+}
+
+// verifyPreRunE is like lockRunE but falls back to the managed block
+// of .gitignore when there is no lockfile.
+func (c *command) verifyPreRunE(cmd *cobra.Command, args []string) error {
+	_, err := os.Stat(lockfile.Name)
+	if err == nil {
+		return c.lockRunE(cmd, args)
+	}
+
+	if !os.IsNotExist(err) {
+		return errors.Wrap(err, "cmd: stat lockfile")
+	}
+
+	doc, err := readIgnoreFile()
+	if err != nil {
+		return err
+	}
+
+	if doc.Block == nil {
+		return errors.Errorf("cmd: neither %s nor a managed block in %s found", lockfile.Name, ignoreFile)
+	}
+
+	c.lock = &lockfile.Lock{Templates: doc.Block.Templates, Commit: doc.Block.Commit}
+	c.commitHash = doc.Block.Commit
+	c.ref = ""
+
+	return c.rootRunE(cmd, args)
+}
+
+func (c *command) verifyRunE(cmd *cobra.Command, args []string) error {
+	actual, err := ioutil.ReadFile(ignoreFile)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "cmd: read file")
+	}
+
+	doc, err := block.Parse(actual)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := c.layers.Generate(&body, c.lock.Templates...); err != nil {
+		return err
+	}
+
+	expected := doc.Render(block.Block{Templates: c.lock.Templates, Commit: c.commitHash}, body.Bytes())
+
+	if bytes.Equal(actual, expected) {
+		return nil
+	}
+
+	if err := writeDiff(c.output, actual, expected); err != nil {
+		return err
+	}
+
+	return errors.Errorf("cmd: %s is not up to date", ignoreFile)
+}
+
+// writeDiff writes the unified diff between the current content of .gitignore
+// and its expected content to w.
+func writeDiff(w io.Writer, current, expected []byte) error {
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(string(expected)),
+		FromFile: ignoreFile,
+		ToFile:   ignoreFile + " (generated)",
+		Context:  3, //nolint:gomnd
+	}
+
+	return errors.Wrap(difflib.WriteUnifiedDiff(w, diff), "cmd: write diff")
+}
@@ -0,0 +1,71 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n*.test\n",
+		"templates/order":        "",
+	})
+
+	g := newGig(t)
+	url := "--source=file://" + filepath.ToSlash(source.dir)
+
+	_, err := g.run(url, "verify")
+	assert.EqualError(t, err, "cmd: neither "+lockfile.Name+" nor a managed block in .gitignore found")
+
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte("/bin/\n"), 0600))
+
+	_, err = g.run(url, "gen", "--file", "go")
+	require.NoError(t, err)
+
+	out, err := g.run(url, "verify")
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	ignoreFile := g.readFile(".gitignore")
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte(strings.Replace(ignoreFile, "*.test\n", "", 1)), 0600))
+
+	out, err = g.run(url, "verify")
+	assert.EqualError(t, err, "cmd: .gitignore is not up to date")
+	assert.Equal(t, `--- .gitignore
++++ .gitignore (generated)
+@@ -4,5 +4,6 @@
+ 
+ ### Go ###
+ *.exe
++*.test
+ # gig:end
+ 
+`, out)
+
+	// Without the lockfile the managed block pins the templates.
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte(ignoreFile), 0600))
+	require.NoError(t, os.Remove(lockfile.Name))
+
+	source.commit(map[string]string{"templates/Go.gitignore": "*.out\n"})
+
+	_, err = g.run(url, "update")
+	require.NoError(t, err)
+
+	out, err = g.run(url, "verify")
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	require.NoError(t, ioutil.WriteFile(".gitignore", []byte(strings.Replace(ignoreFile, "*.exe\n", "", 1)), 0600))
+
+	out, err = g.run(url, "verify")
+	assert.EqualError(t, err, "cmd: .gitignore is not up to date")
+	assert.Contains(t, out, "+*.exe\n")
+}
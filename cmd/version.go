@@ -23,20 +23,69 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/embedded"
+	"github.com/shihanng/gig/internal/repo"
 	"github.com/spf13/cobra"
 )
 
 func newVersionCmd(c *command) *cobra.Command {
 	return &cobra.Command{
-		Use:   "version",
-		Short: "Print the version number and other useful info",
-		Run:   c.versionRunE,
+		Use:         "version",
+		Short:       "Print the version number and other useful info",
+		Run:         c.versionRunE,
+		Annotations: map[string]string{readOnlyAnnotation: ""},
 	}
 }
 
 func (c *command) versionRunE(cmd *cobra.Command, args []string) {
 	fmt.Fprintf(c.output, "gig version %s\n", c.version)
-	fmt.Fprintf(c.output, "Cached github.com/toptal/gitignore in: %s\n", c.cachePath)
-	fmt.Fprintf(c.output, "Using github.com/toptal/gitignore commit hash: %s\n", c.commitHash)
+	fmt.Fprintf(c.output, "Cached %s in: %s\n", repo.DisplaySource(c.source), c.repoPath())
+	fmt.Fprintf(c.output, "Using %s commit hash: %s\n", repo.DisplaySource(c.source), c.commitHash)
+
+	if c.signer != "" {
+		fmt.Fprintf(c.output, "Commit signed by: %s\n", c.signer)
+	}
+
+	if c.embedded != nil {
+		fmt.Fprintf(c.output, "Templates snapshot embedded in gig, created at: %s\n",
+			c.embedded.Created.Local().Format(time.RFC3339))
+
+		return
+	}
+
+	// A gig built with go build or go install instead of a release
+	// has no snapshot to fall back on.
+	if _, _, err := c.openEmbedded(); errors.Is(err, embedded.ErrNotEmbedded) {
+		fmt.Fprintln(c.output, "No templates snapshot embedded in gig, it needs to clone the templates")
+	}
+
+	if c.repo == nil {
+		c.printSnapshotInfo()
+
+		return
+	}
+
+	lastFetch, err := repo.LastFetch(c.repo)
+	if err != nil || lastFetch.IsZero() {
+		fmt.Fprintln(c.output, "Templates last fetched at: unknown")
+
+		return
+	}
+
+	fmt.Fprintf(c.output, "Templates last fetched at: %s (%s ago)\n",
+		lastFetch.Local().Format(time.RFC3339), time.Since(lastFetch).Round(time.Second))
+}
+
+func (c *command) printSnapshotInfo() {
+	info, err := repo.Stat(c.repoPath(), c.repoOptions()...)
+	if err != nil || info.LastFetch == nil {
+		fmt.Fprintln(c.output, "Templates snapshot created at: unknown")
+
+		return
+	}
+
+	fmt.Fprintf(c.output, "Templates snapshot created at: %s\n", info.LastFetch.Local().Format(time.RFC3339))
 }
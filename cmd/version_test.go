@@ -0,0 +1,23 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion_NotEmbedded(t *testing.T) {
+	source := newSourceRepo(t)
+	hash := source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "",
+	})
+
+	g := newGig(t)
+
+	out, err := g.run("--source", source.dir, "version")
+	require.NoError(t, err)
+	assert.Contains(t, out, "commit hash: "+hash+"\n")
+	assert.Contains(t, out, "No templates snapshot embedded in gig")
+}
@@ -0,0 +1,178 @@
+// Package block handles the part of a .gitignore file that is managed by gig.
+// The managed block is wrapped in marker comments so that regenerating it
+// keeps every rule written by hand outside of it:
+//
+//	# my own rules
+//	/bin
+//
+//	# gig:begin templates=go,elm commit=f0bddaeda3368130d52bde2b62a9df741f6117d4
+//	...
+//	# gig:end
+package block
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	beginMarker = "# gig:begin"
+	endMarker   = "# gig:end"
+)
+
+// Block describes how the managed block was generated.
+type Block struct {
+	Templates []string
+	Commit    string
+}
+
+// Document is the content of a .gitignore file split around its managed block.
+type Document struct {
+	Before []byte
+	Body   []byte
+	After  []byte
+
+	// Block is nil when the document does not contain a managed block.
+	Block *Block
+}
+
+// Parse splits content around the managed block. A document without
+// a managed block is returned with all its content in Before.
+func Parse(content []byte) (*Document, error) {
+	doc := &Document{}
+
+	var (
+		offset int
+		begin  = -1
+		body   = -1
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Split(scanLines)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		start := offset
+		offset += len(line)
+		trimmed := strings.TrimSpace(string(line))
+
+		switch {
+		case strings.HasPrefix(trimmed, beginMarker):
+			if begin >= 0 {
+				return nil, errors.New("block: found nested begin marker")
+			}
+
+			b, err := parseMarker(strings.TrimPrefix(trimmed, beginMarker))
+			if err != nil {
+				return nil, err
+			}
+
+			begin, body, doc.Block = start, offset, b
+		case trimmed == endMarker:
+			if begin < 0 {
+				return nil, errors.New("block: found end marker without begin marker")
+			}
+
+			doc.Before = content[:begin]
+			doc.Body = content[body:start]
+			doc.After = content[offset:]
+
+			return doc, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "block: scanning")
+	}
+
+	if begin >= 0 {
+		return nil, errors.New("block: missing end marker")
+	}
+
+	doc.Before = content
+
+	return doc, nil
+}
+
+// Render returns the content of the document where the managed block
+// is replaced by b with body as its content. When the document does not
+// have a managed block yet, the block is appended to the end.
+func (d *Document) Render(b Block, body []byte) []byte {
+	var buf bytes.Buffer
+
+	buf.Write(d.Before)
+
+	if d.Block == nil && len(d.Before) > 0 {
+		if !bytes.HasSuffix(d.Before, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString(b.marker() + "\n")
+	buf.Write(body)
+
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString(endMarker + "\n")
+	buf.Write(d.After)
+
+	return buf.Bytes()
+}
+
+func (b Block) marker() string {
+	marker := fmt.Sprintf("%s templates=%s", beginMarker, strings.Join(b.Templates, ","))
+
+	if b.Commit != "" {
+		marker += " commit=" + b.Commit
+	}
+
+	return marker
+}
+
+func parseMarker(attrs string) (*Block, error) {
+	b := &Block{}
+
+	for _, field := range strings.Fields(attrs) {
+		kv := strings.SplitN(field, "=", 2) //nolint:gomnd
+		if len(kv) != 2 {                   //nolint:gomnd
+			return nil, errors.Errorf("block: malformed attribute %q", field)
+		}
+
+		switch kv[0] {
+		case "templates":
+			if kv[1] != "" {
+				b.Templates = strings.Split(kv[1], ",")
+			}
+		case "commit":
+			b.Commit = kv[1]
+		}
+	}
+
+	return b, nil
+}
+
+// scanLines is like bufio.ScanLines but keeps the line endings so that
+// the offsets of the scanned lines can be tracked.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,130 @@
+package block_test
+
+import (
+	"testing"
+
+	"github.com/shihanng/gig/internal/block"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	type args struct {
+		content string
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		want      *block.Document
+		assertion assert.ErrorAssertionFunc
+	}{
+		{
+			name: "without block",
+			args: args{
+				content: "/bin\n",
+			},
+			want: &block.Document{
+				Before: []byte("/bin\n"),
+			},
+			assertion: assert.NoError,
+		},
+		{
+			name: "with block",
+			args: args{
+				content: "/bin\n# gig:begin templates=go,elm commit=abc\n\n### Go ###\n# gig:end\n/vendor\n",
+			},
+			want: &block.Document{
+				Before: []byte("/bin\n"),
+				Body:   []byte("\n### Go ###\n"),
+				After:  []byte("/vendor\n"),
+				Block: &block.Block{
+					Templates: []string{"go", "elm"},
+					Commit:    "abc",
+				},
+			},
+			assertion: assert.NoError,
+		},
+		{
+			name: "missing end marker",
+			args: args{
+				content: "# gig:begin templates=go\n",
+			},
+			want:      nil,
+			assertion: assert.Error,
+		},
+		{
+			name: "end marker without begin",
+			args: args{
+				content: "# gig:end\n",
+			},
+			want:      nil,
+			assertion: assert.Error,
+		},
+		{
+			name: "malformed marker",
+			args: args{
+				content: "# gig:begin templates\n# gig:end\n",
+			},
+			want:      nil,
+			assertion: assert.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := block.Parse([]byte(tt.args.content))
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDocument_Render(t *testing.T) {
+	type args struct {
+		content string
+		block   block.Block
+		body    string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "empty file",
+			args: args{
+				content: "",
+				block:   block.Block{Templates: []string{"go"}},
+				body:    "\n### Go ###\n",
+			},
+			want: "# gig:begin templates=go\n\n### Go ###\n# gig:end\n",
+		},
+		{
+			name: "append to hand-written rules",
+			args: args{
+				content: "/bin",
+				block:   block.Block{Templates: []string{"go"}, Commit: "abc"},
+				body:    "\n### Go ###",
+			},
+			want: "/bin\n\n# gig:begin templates=go commit=abc\n\n### Go ###\n# gig:end\n",
+		},
+		{
+			name: "replace existing block",
+			args: args{
+				content: "/bin\n# gig:begin templates=go\n\n### Go ###\n# gig:end\n/vendor\n",
+				block:   block.Block{Templates: []string{"elm"}},
+				body:    "\n### Elm ###\n",
+			},
+			want: "/bin\n# gig:begin templates=elm\n\n### Elm ###\n# gig:end\n/vendor\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := block.Parse([]byte(tt.args.content))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(doc.Render(tt.args.block, []byte(tt.args.body))))
+		})
+	}
+}
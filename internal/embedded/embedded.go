@@ -0,0 +1,46 @@
+// Package embedded gives access to the snapshot of the templates of
+// repo.SourceRepo that is built into gig, so that gig works before
+// the templates could be cached.
+package embedded
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/repo"
+)
+
+//go:generate go run ./gen -o data/snapshot.tar.gz
+
+// data contains the snapshot created by go generate, only the placeholder
+// README.md when gig was built without running it.
+//
+//go:embed data
+var data embed.FS //nolint:gochecknoglobals
+
+const snapshotFile = "data/snapshot.tar.gz"
+
+// ErrNotEmbedded is returned by Open when gig was built without a snapshot.
+var ErrNotEmbedded = errors.New("embedded: no templates snapshot embedded")
+
+// Open returns the embedded snapshot and its files.
+func Open() (*repo.Snapshot, fs.FS, error) {
+	return open(data)
+}
+
+// open returns the snapshot embedded in fsys, see data.
+func open(fsys fs.FS) (*repo.Snapshot, fs.FS, error) {
+	f, err := fsys.Open(snapshotFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotEmbedded
+	}
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "embedded: open snapshot")
+	}
+
+	defer f.Close()
+
+	return repo.ReadSnapshot(f)
+}
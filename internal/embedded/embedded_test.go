@@ -0,0 +1,65 @@
+package embedded_test
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/embedded"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	s, fsys, err := embedded.Open()
+	if errors.Is(err, embedded.ErrNotEmbedded) {
+		t.Skip("built without a templates snapshot, run go generate first")
+	}
+
+	require.NoError(t, err)
+	assert.Equal(t, repo.SourceRepo, s.Source)
+	assert.Len(t, s.Commit, 40)
+
+	_, err = fs.Stat(fsys, "templates/order")
+	assert.NoError(t, err)
+}
+
+func TestOpenFS(t *testing.T) {
+	want := &repo.Snapshot{
+		Commit:  "85ec4e7c71f2fdde1e2bd148d7f36d6458da12cc",
+		Source:  repo.SourceRepo,
+		Created: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, repo.WriteSnapshot(&buf, want, fstest.MapFS{
+		"templates/Go.gitignore": {Data: []byte("*.exe\n")},
+		"templates/order":        {Data: []byte("go\n")},
+		"README.md":              {Data: []byte("# gitignore\n")},
+	}, "templates"))
+
+	s, fsys, err := embedded.OpenFS(fstest.MapFS{"data/snapshot.tar.gz": {Data: buf.Bytes()}})
+	require.NoError(t, err)
+	assert.Equal(t, want.Commit, s.Commit)
+	assert.Equal(t, want.Source, s.Source)
+	assert.True(t, want.Created.Equal(s.Created))
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	_, err = fs.Stat(fsys, "README.md")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "only the templates are in the snapshot")
+
+	_, _, err = embedded.OpenFS(fstest.MapFS{"data/README.md": {Data: []byte("placeholder\n")}})
+	assert.ErrorIs(t, err, embedded.ErrNotEmbedded)
+
+	_, _, err = embedded.OpenFS(fstest.MapFS{"data/snapshot.tar.gz": {Data: []byte("not a snapshot")}})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, embedded.ErrNotEmbedded)
+}
@@ -0,0 +1,4 @@
+package embedded
+
+// OpenFS is exported for the tests of open with a fixture snapshot.
+var OpenFS = open //nolint:gochecknoglobals
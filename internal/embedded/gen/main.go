@@ -0,0 +1,76 @@
+// Command gen clones the templates repository and writes the snapshot
+// of its templates directory that is embedded into gig.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/repo"
+)
+
+func main() {
+	output := flag.String("o", "snapshot.tar.gz", "output file")
+	source := flag.String("source", repo.SourceRepo, "templates repository, e.g. a mirror")
+	ref := flag.String("ref", "", "reference of the templates, the default branch when empty")
+	flag.Parse()
+
+	if err := run(*output, *source, *ref); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(output, source, ref string) (err error) {
+	dir, err := ioutil.TempDir("", "gig-embedded")
+	if err != nil {
+		return errors.Wrap(err, "gen: create temporary directory")
+	}
+
+	defer os.RemoveAll(dir)
+
+	r, err := repo.New(filepath.Join(dir, "templates"), source)
+	if err != nil {
+		return err
+	}
+
+	hash, fsys, err := repo.Checkout(r, ref)
+	if err != nil {
+		return err
+	}
+
+	tmp := output + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrap(err, "gen: create snapshot")
+	}
+
+	defer os.Remove(tmp) //nolint:errcheck
+
+	err = repo.WriteSnapshot(f, &repo.Snapshot{
+		Commit:  hash,
+		Source:  repo.RedactSource(source),
+		Created: time.Now().UTC().Truncate(time.Second),
+	}, fsys, "templates")
+	if cerr := f.Close(); err == nil {
+		err = errors.Wrap(cerr, "gen: write snapshot")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, output); err != nil {
+		return errors.Wrap(err, "gen: move snapshot into place")
+	}
+
+	fmt.Printf("Wrote snapshot of commit hash %s to %s\n", hash, output)
+
+	return nil
+}
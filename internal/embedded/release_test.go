@@ -0,0 +1,17 @@
+//go:build release
+
+package embedded_test
+
+import (
+	"testing"
+
+	"github.com/shihanng/gig/internal/embedded"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpen_Release fails instead of skipping when the snapshot is missing,
+// so that no release is built without it.
+func TestOpen_Release(t *testing.T) {
+	_, _, err := embedded.Open()
+	require.NoError(t, err, "run go generate ./internal/embedded before building a release")
+}
@@ -2,10 +2,12 @@ package file
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -19,45 +21,127 @@ type File struct {
 	Typ  string
 }
 
-func List(directory string) ([]string, error) {
-	files, err := ioutil.ReadDir(directory)
+// Layer is a directory of templates, e.g. the cached upstream templates
+// or the custom templates of a project. Categories optionally maps
+// the canonical names of the templates to their categories.
+// Source marks the layers of the template repositories, see Layers.
+type Layer struct {
+	Name       string
+	FS         fs.FS
+	Categories map[string]string
+	Source     bool
+}
+
+// Layers are directories of templates in the order of their precedence.
+// The .gitignore file of a template is taken from the first layer that has it
+// while the .patch and .stack files of every layer are appended to it,
+// those of the first layer last. Source layers are alternatives instead:
+// only the first source layer with the .gitignore file of a template
+// adds its files, unless the template is qualified with another layer.
+type Layers []Layer
+
+// Qualified returns the names of the templates in all layers qualified
+// with the name of their layer, e.g. corp/go for the template Go of
+// the layer corp. Unlike the names returned by List, they refer to
+// the template of that layer even if another layer has one of the same name.
+func (l Layers) Qualified() ([]string, error) {
+	var names []string
+
+	for _, layer := range l {
+		templates, err := List(layer.FS)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range templates {
+			names = append(names, Canon(layer.Name+"/"+t))
+		}
+	}
+
+	return names, nil
+}
+
+// Template is a template and the name of the layer its .gitignore file comes from.
+type Template struct {
+	Name  string
+	Layer string
+}
+
+// List returns the canonical names of the templates in fsys.
+func List(fsys fs.FS) ([]string, error) {
+	return Layers{{FS: fsys}}.List()
+}
+
+// List returns the canonical names of the templates in all layers.
+func (l Layers) List() ([]string, error) {
+	templates, err := l.Templates()
 	if err != nil {
-		return nil, errors.Wrap(err, "file: read directory for list")
+		return nil, err
 	}
 
-	var names []string
+	names := make([]string, 0, len(templates))
 
-	collected := map[string]struct{}{}
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
 
-	for _, f := range files {
-		filename := f.Name()
-		ext := filepath.Ext(filename)
+	return names, nil
+}
 
-		if ext != ".gitignore" {
-			continue
+// Templates returns the templates in all layers sorted by their canonical names.
+func (l Layers) Templates() ([]Template, error) {
+	var templates []Template
+
+	collected := map[string]struct{}{}
+
+	for _, layer := range l {
+		files, err := fs.ReadDir(layer.FS, ".")
+		if err != nil {
+			return nil, errors.Wrap(err, "file: read directory for list")
 		}
 
-		base := strings.TrimSuffix(filename, ext)
-		name := Canon(base)
+		for _, f := range files {
+			filename := f.Name()
+			ext := filepath.Ext(filename)
+
+			if ext != ".gitignore" {
+				continue
+			}
+
+			base := strings.TrimSuffix(filename, ext)
+			name := Canon(base)
 
-		if _, found := collected[name]; !found {
-			names = append(names, name)
-			collected[name] = struct{}{}
+			if _, found := collected[name]; !found {
+				templates = append(templates, Template{Name: name, Layer: layer.Name})
+				collected[name] = struct{}{}
+			}
 		}
 	}
 
-	sort.Strings(names)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
 
-	return names, nil
+	return templates, nil
+}
+
+// layerFile is a file in the directory of a layer.
+type layerFile struct {
+	layer string
+	fsys  fs.FS
+	name  string
 }
 
 type IgnoreFile struct {
-	gitignore string
-	patch     string
-	stack     []string
+	gitignore layerFile
+	patch     []layerFile
+	stack     []layerFile
+}
+
+// files returns the files of the template in the order they are written.
+func (f IgnoreFile) files() []layerFile {
+	return append(append([]layerFile{f.gitignore}, f.patch...), f.stack...)
 }
 
-func lookup(directory string, items []string) ([]string, map[string]IgnoreFile, error) {
+func lookup(layers Layers, items []string) ([]string, map[string]IgnoreFile, error) {
 	ignoreFiles := make(map[string]IgnoreFile)
 	unique := make([]string, 0, len(items))
 
@@ -71,42 +155,114 @@ func lookup(directory string, items []string) ([]string, map[string]IgnoreFile,
 		unique = append(unique, item)
 	}
 
-	files, err := ioutil.ReadDir(directory)
+	sources, err := sourceLayers(layers, ignoreFiles)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "file: read directory")
+		return nil, nil, err
 	}
 
-	for _, f := range files {
-		filename := f.Name()
-		ext := filepath.Ext(filename)
-		base := strings.TrimSuffix(filename, ext)
-		splitted := strings.Split(base, ".")
-
-		if ignoreFile, ok := ignoreFiles[Canon(splitted[0])]; ok {
-			switch Canon(ext) {
-			case ".gitignore":
-				ignoreFile.gitignore = filename
-			case ".patch":
-				ignoreFile.patch = filename
-			case ".stack":
-				ignoreFile.stack = append(ignoreFile.stack, filename)
+	// Start with the layer of the lowest precedence so that the others
+	// override its .gitignore files and append to its .patch and .stack files.
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer, fsys := layers[i].Name, layers[i].FS
+
+		files, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "file: read directory")
+		}
+
+		patches := make(map[string]layerFile)
+
+		for _, f := range files {
+			filename := f.Name()
+			ext := filepath.Ext(filename)
+			base := strings.TrimSuffix(filename, ext)
+			splitted := strings.Split(base, ".")
+
+			// A qualified item, e.g. corp/Go, only matches the files of its layer.
+			for _, key := range []string{Canon(splitted[0]), Canon(layer + "/" + splitted[0])} {
+				ignoreFile, ok := ignoreFiles[key]
+				if !ok {
+					continue
+				}
+
+				if layers[i].Source && key == Canon(splitted[0]) && sources[key] != layer {
+					continue
+				}
+
+				switch Canon(ext) {
+				case ".gitignore":
+					ignoreFile.gitignore = layerFile{layer: layer, fsys: fsys, name: filename}
+				case ".patch":
+					patches[key] = layerFile{layer: layer, fsys: fsys, name: filename}
+				case ".stack":
+					ignoreFile.stack = append(ignoreFile.stack, layerFile{layer: layer, fsys: fsys, name: filename})
+				}
+
+				ignoreFiles[key] = ignoreFile
 			}
+		}
 
-			ignoreFiles[Canon(splitted[0])] = ignoreFile
+		for name, patch := range patches {
+			ignoreFile := ignoreFiles[name]
+			ignoreFile.patch = append(ignoreFile.patch, patch)
+			ignoreFiles[name] = ignoreFile
 		}
 	}
 
 	return unique, ignoreFiles, nil
 }
 
-func Generate(w io.Writer, directory string, items ...string) error {
-	uniqueItems, ignoreFiles, err := lookup(directory, items)
+// sourceLayers returns the name of the first source layer that has
+// the .gitignore file of the unqualified templates of ignoreFiles.
+func sourceLayers(layers Layers, ignoreFiles map[string]IgnoreFile) (map[string]string, error) {
+	sources := make(map[string]string)
+
+	for _, layer := range layers {
+		if !layer.Source {
+			continue
+		}
+
+		files, err := fs.ReadDir(layer.FS, ".")
+		if err != nil {
+			return nil, errors.Wrap(err, "file: read directory")
+		}
+
+		for _, f := range files {
+			filename := f.Name()
+			ext := filepath.Ext(filename)
+
+			if Canon(ext) != ".gitignore" {
+				continue
+			}
+
+			key := Canon(strings.Split(strings.TrimSuffix(filename, ext), ".")[0])
+			if _, ok := ignoreFiles[key]; !ok {
+				continue
+			}
+
+			if _, found := sources[key]; !found {
+				sources[key] = layer.Name
+			}
+		}
+	}
+
+	return sources, nil
+}
+
+// Generate writes the content of the templates items found in fsys to w.
+func Generate(w io.Writer, fsys fs.FS, items ...string) error {
+	return Layers{{FS: fsys}}.Generate(w, items...)
+}
+
+// Generate writes the content of the templates items found in the layers to w.
+// Items can be qualified with the name of a layer, see Qualified.
+func (l Layers) Generate(w io.Writer, items ...string) error {
+	uniqueItems, ignoreFiles, err := lookup(l, items)
 	if err != nil {
 		return err
 	}
 
 	writer := writer{
-		directory:  directory,
 		duplicates: make(map[string]bool),
 	}
 	ew := &errWriter{w: w}
@@ -116,7 +272,7 @@ func Generate(w io.Writer, directory string, items ...string) error {
 	for _, item := range uniqueItems {
 		ignoreFile := ignoreFiles[Canon(item)]
 
-		if ignoreFile.gitignore == "" {
+		if ignoreFile.gitignore.name == "" {
 			ew.fprintf("\n#!! ERROR: %s is undefined !!#\n", item)
 
 			errs = multierror.Append(errs, errors.Errorf("file: %s is undefined", item))
@@ -124,8 +280,7 @@ func Generate(w io.Writer, directory string, items ...string) error {
 			continue
 		}
 
-		files := append([]string{ignoreFile.gitignore, ignoreFile.patch}, ignoreFile.stack...)
-		if err := writer.Write(ew, files...); err != nil {
+		if err := writer.Write(ew, ignoreFile.files()...); err != nil {
 			return err
 		}
 	}
@@ -137,25 +292,58 @@ func Generate(w io.Writer, directory string, items ...string) error {
 	return errs.ErrorOrNil()
 }
 
+// Checksums returns the hex encoded SHA-256 checksums of the files that
+// Generate reads for items, keyed by the name of their layer and the file
+// name, e.g. upstream/Go.gitignore, or only the file name for a layer
+// without name. Undefined items are skipped.
+func (l Layers) Checksums(items ...string) (map[string]string, error) {
+	uniqueItems, ignoreFiles, err := lookup(l, items)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string)
+
+	for _, item := range uniqueItems {
+		ignoreFile := ignoreFiles[Canon(item)]
+
+		if ignoreFile.gitignore.name == "" {
+			continue
+		}
+
+		for _, f := range ignoreFile.files() {
+			content, err := fs.ReadFile(f.fsys, f.name)
+			if err != nil {
+				return nil, errors.Wrapf(err, "file: read file: %s", f.name)
+			}
+
+			sum := sha256.Sum256(content)
+			checksums[path.Join(f.layer, f.name)] = hex.EncodeToString(sum[:])
+		}
+	}
+
+	return checksums, nil
+}
+
 type writer struct {
-	directory  string
 	duplicates map[string]bool
 }
 
-func (w *writer) Write(out *errWriter, filenames ...string) error {
+func (w *writer) Write(out *errWriter, files ...layerFile) error {
 	var err error
-	for _, filename := range filenames {
-		if filename == "" || err != nil {
+	for _, f := range files {
+		if err != nil {
 			continue
 		}
 
-		err = func(filename string) error {
+		err = func(f layerFile) error {
+			filename := f.name
 			ext := filepath.Ext(filename)
 			base := strings.TrimSuffix(filename, ext)
 
 			out.fprintf(header(base, ext))
 
-			file, err := os.Open(filepath.Join(w.directory, filename))
+			file, err := f.fsys.Open(filename)
 			if err != nil {
 				return errors.Wrapf(err, "file: open file: %s", filename)
 			}
@@ -175,7 +363,7 @@ func (w *writer) Write(out *errWriter, filenames ...string) error {
 			}
 
 			return errors.Wrap(scanner.Err(), "file: scanning")
-		}(filename)
+		}(f)
 	}
 
 	return err
@@ -208,6 +396,31 @@ func header(name, typ string) string {
 	return fmt.Sprintf("\n### %s %s###\n", name, typ)
 }
 
+// Templates returns the names of the templates found in the headers
+// of the content r that was produced by Generate.
+func Templates(r io.Reader) ([]string, error) {
+	var names []string
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if !strings.HasPrefix(line, "### ") || !strings.HasSuffix(line, " ###") {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "### "), " ###"))
+		if name == "" || strings.HasSuffix(name, " Patch") || strings.HasSuffix(name, " Stack") {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names, errors.Wrap(scanner.Err(), "file: scanning headers")
+}
+
 func Canon(v string) string {
 	return strings.ToLower(v)
 }
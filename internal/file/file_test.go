@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"flag"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -546,7 +547,7 @@ func TestList(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := file.List(tt.args.directory)
+			got, err := file.List(os.DirFS(tt.args.directory))
 			tt.assertion(t, err)
 			assert.Equal(t, tt.want, got)
 		})
@@ -624,7 +625,7 @@ func TestGenerate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := &bytes.Buffer{}
-			err := file.Generate(w, `testdata`, tt.args.items...)
+			err := file.Generate(w, os.DirFS(tt.args.directory), tt.args.items...)
 			tt.assertion(t, err)
 
 			goldenPath := filepath.Join(`_golden`, tt.wantW)
@@ -642,5 +643,56 @@ func TestGenerate(t *testing.T) {
 
 func TestGenerate_UnknownDirectory(t *testing.T) {
 	w := &bytes.Buffer{}
-	assert.Error(t, file.Generate(w, `unknown`))
+	assert.Error(t, file.Generate(w, os.DirFS(`unknown`)))
+}
+
+func TestTemplates(t *testing.T) {
+	type args struct {
+		golden string
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		want      []string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{
+			name: "with patch",
+			args: args{
+				golden: "with-patch.golden",
+			},
+			want:      []string{"Go", "Elm"},
+			assertion: assert.NoError,
+		},
+		{
+			name: "with stack",
+			args: args{
+				golden: "with-stack.golden",
+			},
+			want:      []string{"LAMP"},
+			assertion: assert.NoError,
+		},
+		{
+			name: "with undefined",
+			args: args{
+				golden: "with-undefined.golden",
+			},
+			want:      []string{"Go"},
+			assertion: assert.NoError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join(`_golden`, tt.args.golden))
+			require.NoError(t, err)
+
+			defer f.Close()
+
+			got, err := file.Templates(f)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
 }
@@ -0,0 +1,169 @@
+package file_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/shihanng/gig/internal/file"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLayers() file.Layers {
+	return file.Layers{
+		{
+			Name: "project",
+			FS: fstest.MapFS{
+				"Go.gitignore":          {Data: []byte("vendor/\n")},
+				"Terraform.patch":       {Data: []byte("*.tfplan\n")},
+				"OurMonorepo.gitignore": {Data: []byte("/out\n")},
+			},
+		},
+		{
+			Name: "upstream",
+			FS: fstest.MapFS{
+				"Go.gitignore":        {Data: []byte("*.exe\n")},
+				"Go.patch":            {Data: []byte("*.test\n")},
+				"Terraform.gitignore": {Data: []byte(".terraform/\n")},
+				"Terraform.patch":     {Data: []byte("*.tfstate\n")},
+			},
+		},
+	}
+}
+
+func TestLayers_Templates(t *testing.T) {
+	got, err := newLayers().Templates()
+	assert.NoError(t, err)
+	assert.Equal(t, []file.Template{
+		{Name: "go", Layer: "project"},
+		{Name: "ourmonorepo", Layer: "project"},
+		{Name: "terraform", Layer: "upstream"},
+	}, got)
+}
+
+func TestLayers_Generate(t *testing.T) {
+	w := &bytes.Buffer{}
+	assert.NoError(t, newLayers().Generate(w, "go", "terraform", "ourmonorepo"))
+	assert.Equal(t, `
+### Go ###
+vendor/
+
+### Go Patch ###
+*.test
+
+### Terraform ###
+.terraform/
+
+### Terraform Patch ###
+*.tfstate
+
+### Terraform Patch ###
+*.tfplan
+
+### OurMonorepo ###
+/out
+`, w.String())
+}
+
+func TestLayers_Qualified(t *testing.T) {
+	got, err := newLayers().Qualified()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"project/go",
+		"project/ourmonorepo",
+		"upstream/go",
+		"upstream/terraform",
+	}, got)
+}
+
+func TestLayers_Generate_Qualified(t *testing.T) {
+	w := &bytes.Buffer{}
+	err := newLayers().Generate(w, "upstream/Go", "project/Terraform", "corp/Go")
+	assert.Error(t, err)
+	assert.Equal(t, `
+### Go ###
+*.exe
+
+### Go Patch ###
+*.test
+
+#!! ERROR: project/Terraform is undefined !!#
+
+#!! ERROR: corp/Go is undefined !!#
+`, w.String())
+}
+
+func TestLayers_Checksums(t *testing.T) {
+	got, err := newLayers().Checksums("go", "terraform", "undefined")
+	assert.NoError(t, err)
+	// The Go.gitignore of upstream is overridden and not read.
+	assert.Equal(t, map[string]string{
+		"project/Go.gitignore":         "e15b1b0e0f10012abb6f06891455da689a7a4ff323b70ce46144d8611b7d4d8a",
+		"upstream/Go.patch":            "50d82191031c995157d6d20cd52e35a1578fd11710632f49b8c6642491d434d2",
+		"upstream/Terraform.gitignore": "b0f6b499b6db3c9f38760baa758e6e7dffa1b149a342b98e87c3cdc705a66102",
+		"upstream/Terraform.patch":     "09033f633d82891b09f059f101bd689932d7ddecba562c4c5a7769a707ee820b",
+		"project/Terraform.patch":      "ccfff074c7f2252f8bf5b30000d2c8259e358b29e413806a9abf2999d3b87f52",
+	}, got)
+}
+
+func TestLayers_Generate_Sources(t *testing.T) {
+	upstream := fstest.MapFS{
+		"Go.gitignore":        {Data: []byte("*.exe\n")},
+		"Go.patch":            {Data: []byte("*.test\n")},
+		"Terraform.gitignore": {Data: []byte(".terraform/\n")},
+	}
+
+	layers := file.Layers{
+		{
+			Name: "project",
+			FS: fstest.MapFS{
+				"Go.patch": {Data: []byte("/bin/\n")},
+			},
+		},
+		{
+			Name: "corp",
+			FS: fstest.MapFS{
+				"Go.gitignore": {Data: []byte("*.exe\n*.dll\n")},
+				"Go.patch":     {Data: []byte("*.out\n")},
+			},
+			Source: true,
+		},
+		{Name: "upstream", FS: upstream, Source: true},
+	}
+
+	// Go is resolved to corp, without the files of upstream,
+	// while the custom templates still add to it. The rules
+	// written already are left out of upstream/go.
+	w := &bytes.Buffer{}
+	assert.NoError(t, layers.Generate(w, "go", "terraform", "upstream/go"))
+	assert.Equal(t, `
+### Go ###
+*.exe
+*.dll
+
+### Go Patch ###
+*.out
+
+### Go Patch ###
+/bin/
+
+### Terraform ###
+.terraform/
+
+### Go ###
+
+### Go Patch ###
+*.test
+`, w.String())
+
+	// A mirror of upstream does not duplicate its patches.
+	layers[1].FS = upstream
+
+	got, err := layers.Checksums("go")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"corp/Go.gitignore": "a5270f91138fc2bb5470ecb521dab043140d7e0fd8cb33bb0644ac13efb60fe7",
+		"corp/Go.patch":     "50d82191031c995157d6d20cd52e35a1578fd11710632f49b8c6642491d434d2",
+		"project/Go.patch":  "b7b5a9a5bdb3a876455f7320f221592c77a378fac89c0283dc6ebe19b124bf43",
+	}, got)
+}
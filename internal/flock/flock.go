@@ -0,0 +1,116 @@
+// Package flock provides an advisory lock backed by a file
+// to serialize the access to a resource across processes.
+package flock
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// pollInterval is how often a held lock is retried.
+const pollInterval = 100 * time.Millisecond
+
+// ErrTimeout is returned when the lock could not be acquired in time.
+var ErrTimeout = errors.New("flock: timed out waiting for lock")
+
+// Lock is a lock on the file in Path. A Lock is not safe for concurrent use,
+// each goroutine or process should use its own Lock of the same path.
+type Lock struct {
+	Path string
+
+	file *os.File
+}
+
+// New returns a lock on the file in path. The file is created
+// when the lock is acquired and left in place when it is released.
+func New(path string) *Lock {
+	return &Lock{Path: path}
+}
+
+// Lock acquires an exclusive lock. It waits at most timeout for the lock
+// to be released by others, a non-positive timeout waits forever.
+// onWait, if not nil, is called once when the lock is held by others.
+func (l *Lock) Lock(timeout time.Duration, onWait func()) error {
+	return l.lock(context.Background(), true, timeout, onWait)
+}
+
+// RLock is like Lock but acquires a shared lock that can be held
+// by several readers at the same time.
+func (l *Lock) RLock(timeout time.Duration, onWait func()) error {
+	return l.lock(context.Background(), false, timeout, onWait)
+}
+
+// LockContext is like Lock but also stops waiting when ctx is done.
+func (l *Lock) LockContext(ctx context.Context, timeout time.Duration, onWait func()) error {
+	return l.lock(ctx, true, timeout, onWait)
+}
+
+// RLockContext is like RLock but also stops waiting when ctx is done.
+func (l *Lock) RLockContext(ctx context.Context, timeout time.Duration, onWait func()) error {
+	return l.lock(ctx, false, timeout, onWait)
+}
+
+// Unlock releases the lock. It is safe to call on a nil Lock.
+func (l *Lock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	err := unlock(l.file)
+	if errClose := l.file.Close(); err == nil {
+		err = errClose
+	}
+
+	l.file = nil
+
+	return errors.Wrap(err, "flock: unlock")
+}
+
+func (l *Lock) lock(ctx context.Context, exclusive bool, timeout time.Duration, onWait func()) error {
+	if l.file != nil {
+		return errors.New("flock: already locked")
+	}
+
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_RDWR, 0600) //nolint:gomnd
+	if err != nil {
+		return errors.Wrap(err, "flock: open lock file")
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for waited := false; ; waited = true {
+		ok, err := tryLock(f, exclusive)
+		if err != nil {
+			f.Close()
+
+			return errors.Wrap(err, "flock: lock")
+		}
+
+		if ok {
+			l.file = f
+
+			return nil
+		}
+
+		if timeout > 0 && time.Now().After(deadline) {
+			f.Close()
+
+			return errors.Wrapf(ErrTimeout, "flock: %s is still locked after %s", l.Path, timeout)
+		}
+
+		if !waited && onWait != nil {
+			onWait()
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+
+			return errors.Wrapf(ctx.Err(), "flock: waiting for %s", l.Path)
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package flock_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shihanng/gig/internal/flock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lockPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "cache.lock")
+}
+
+func TestLock_Exclusive(t *testing.T) {
+	path := lockPath(t)
+
+	var (
+		wg      sync.WaitGroup
+		holders int32
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			l := flock.New(path)
+			if !assert.NoError(t, l.Lock(time.Minute, nil)) {
+				return
+			}
+
+			assert.Equal(t, int32(1), atomic.AddInt32(&holders, 1))
+
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+
+			assert.NoError(t, l.Unlock())
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestLock_Timeout(t *testing.T) {
+	path := lockPath(t)
+
+	holder := flock.New(path)
+	require.NoError(t, holder.Lock(0, nil))
+
+	defer holder.Unlock()
+
+	waited := 0
+
+	err := flock.New(path).Lock(200*time.Millisecond, func() { waited++ })
+	assert.ErrorIs(t, err, flock.ErrTimeout)
+	assert.Equal(t, 1, waited)
+
+	err = flock.New(path).RLock(200*time.Millisecond, nil)
+	assert.ErrorIs(t, err, flock.ErrTimeout)
+}
+
+func TestLockContext_Canceled(t *testing.T) {
+	path := lockPath(t)
+
+	holder := flock.New(path)
+	require.NoError(t, holder.Lock(0, nil))
+
+	defer holder.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := flock.New(path).LockContext(ctx, 0, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = flock.New(path).RLockContext(ctx, 0, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRLock_Shared(t *testing.T) {
+	path := lockPath(t)
+
+	first := flock.New(path)
+	require.NoError(t, first.RLock(0, nil))
+
+	second := flock.New(path)
+	assert.NoError(t, second.RLock(200*time.Millisecond, nil))
+
+	assert.ErrorIs(t, flock.New(path).Lock(200*time.Millisecond, nil), flock.ErrTimeout)
+
+	assert.NoError(t, first.Unlock())
+	assert.NoError(t, second.Unlock())
+	assert.NoError(t, flock.New(path).Lock(200*time.Millisecond, nil))
+}
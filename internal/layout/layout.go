@@ -0,0 +1,333 @@
+// Package layout describes how the templates are organized in the
+// repositories gig reads them from, e.g. https://github.com/toptal/gitignore
+// or https://github.com/github/gitignore.
+package layout
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shihanng/gig/internal/file"
+	"github.com/shihanng/gig/internal/order"
+)
+
+// Names of the supported layouts.
+const (
+	Auto   = "auto"
+	Toptal = "toptal"
+	GitHub = "github"
+)
+
+// Layout reads the templates of a repository.
+type Layout interface {
+	// Templates returns the templates of the repository fsys as a flat
+	// directory of .gitignore, .patch, and .stack files, like the one of
+	// https://github.com/toptal/gitignore, and the category of every
+	// template by its canonical name. Categories are nil if the layout has none.
+	Templates(fsys fs.FS) (fs.FS, map[string]string, error)
+
+	// Order returns the special order of the templates returned by Templates,
+	// see order.ReadOrder, or nil if the layout has none.
+	Order(templates fs.FS) (map[string]int, error)
+
+	// Reads reports whether Templates or Order might read the file name
+	// of the repository. The other files are never read.
+	Reads(name string) bool
+
+	// Template returns the name under which Templates exposes the file name
+	// of the repository and whether the file is a template at all.
+	Template(name string) (string, bool)
+}
+
+// Get returns the layout called name. Auto detects the layout of fsys.
+func Get(name string, fsys fs.FS) (Layout, error) {
+	switch name {
+	case Auto, "":
+		return Detect(fsys), nil
+	case Toptal:
+		return toptal{}, nil
+	case GitHub:
+		return github{}, nil
+	}
+
+	return nil, errors.Errorf("layout: unknown layout %s, use one of %s, %s, or %s", name, Auto, Toptal, GitHub)
+}
+
+// Reads returns a function that reports whether the layout called name
+// might read a file of the repository, see Layout.Reads. For Auto it is
+// any file that one of the layouts might read.
+func Reads(name string) (func(name string) bool, error) {
+	if name == Auto || name == "" {
+		return func(name string) bool {
+			return toptal{}.Reads(name) || github{}.Reads(name)
+		}, nil
+	}
+
+	l, err := Get(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Reads, nil
+}
+
+// Detect returns the toptal layout for repositories with a templates
+// directory and the github layout for the others.
+func Detect(fsys fs.FS) Layout {
+	if info, err := fs.Stat(fsys, "templates"); err == nil && info.IsDir() {
+		return toptal{}
+	}
+
+	return github{}
+}
+
+// toptal is the layout of https://github.com/toptal/gitignore,
+// a templates directory with an order file.
+type toptal struct{}
+
+func (toptal) Templates(fsys fs.FS) (fs.FS, map[string]string, error) {
+	if info, err := fs.Stat(fsys, "templates"); err != nil || !info.IsDir() {
+		return nil, nil, errors.Errorf("layout: no templates directory found, the repository does not have the %s layout", Toptal)
+	}
+
+	templates, err := fs.Sub(fsys, "templates")
+
+	return templates, nil, errors.Wrap(err, "layout: open templates directory")
+}
+
+func (toptal) Order(templates fs.FS) (map[string]int, error) {
+	return order.ReadOrder(templates, "order")
+}
+
+func (toptal) Reads(name string) bool {
+	return strings.HasPrefix(name, "templates/")
+}
+
+func (toptal) Template(name string) (string, bool) {
+	dir, base := path.Split(name)
+
+	return base, dir == "templates/"
+}
+
+// github is the layout of https://github.com/github/gitignore, .gitignore files
+// in the root directory and in subdirectories such as Global and community.
+// The subdirectory of a template is its category.
+type github struct{}
+
+func (github) Templates(fsys fs.FS) (fs.FS, map[string]string, error) {
+	flat := flatFS{fsys: fsys, files: make(map[string]string)}
+	categories := make(map[string]string)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if name != "." && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if path.Ext(name) != ".gitignore" {
+			return nil
+		}
+
+		// Templates closer to the root take precedence over those
+		// of the same name in subdirectories.
+		base := path.Base(name)
+		if existing, ok := flat.files[base]; ok && depth(existing) <= depth(name) {
+			return nil
+		}
+
+		flat.files[base] = name
+
+		category := path.Dir(name)
+		if category == "." {
+			category = ""
+		}
+
+		categories[file.Canon(strings.TrimSuffix(base, ".gitignore"))] = category
+
+		return nil
+	})
+
+	return flat, categories, errors.Wrap(err, "layout: find templates")
+}
+
+func (github) Order(templates fs.FS) (map[string]int, error) {
+	return nil, nil
+}
+
+func (github) Reads(name string) bool {
+	if path.Ext(name) != ".gitignore" {
+		return false
+	}
+
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if dir != "." && strings.HasPrefix(dir, ".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Template does not know whether a template is exposed or shadowed by
+// another one of the same name closer to the root, see Templates.
+func (l github) Template(name string) (string, bool) {
+	return path.Base(name), l.Reads(name)
+}
+
+func depth(name string) int {
+	return strings.Count(name, "/")
+}
+
+// flatFS exposes files in subdirectories of fsys in a single directory.
+// files maps the names in the directory to the names in fsys.
+type flatFS struct {
+	fsys  fs.FS
+	files map[string]string
+}
+
+func (f flatFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		entries, err := f.ReadDir(".")
+		if err != nil {
+			return nil, err
+		}
+
+		return &flatDir{entries: entries}, nil
+	}
+
+	target, ok := f.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	file, err := f.fsys.Open(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return renamedFile{File: file, name: name}, nil
+}
+
+func (f flatFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(f.files))
+
+	for name, target := range f.files {
+		info, err := fs.Stat(f.fsys, target)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, fs.FileInfoToDirEntry(renamedInfo{FileInfo: info, name: name}))
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, nil
+}
+
+// renamedFile is a file of a subdirectory exposed under its name in flatFS.
+type renamedFile struct {
+	fs.File
+	name string
+}
+
+func (f renamedFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return renamedInfo{FileInfo: info, name: f.name}, nil
+}
+
+type renamedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i renamedInfo) Name() string {
+	return i.name
+}
+
+// flatDir is the root directory of flatFS.
+type flatDir struct {
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *flatDir) Stat() (fs.FileInfo, error) {
+	return dirInfo{}, nil
+}
+
+func (d *flatDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (d *flatDir) Close() error {
+	return nil
+}
+
+func (d *flatDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+
+	if n <= 0 {
+		d.offset = len(d.entries)
+
+		return rest, nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(rest) {
+		n = len(rest)
+	}
+
+	d.offset += n
+
+	return rest[:n], nil
+}
+
+// dirInfo is the fs.FileInfo of flatDir.
+type dirInfo struct{}
+
+func (dirInfo) Name() string {
+	return "."
+}
+
+func (dirInfo) Size() int64 {
+	return 0
+}
+
+func (dirInfo) Mode() fs.FileMode {
+	return fs.ModeDir | 0555 //nolint:gomnd
+}
+
+func (dirInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (dirInfo) IsDir() bool {
+	return true
+}
+
+func (dirInfo) Sys() interface{} {
+	return nil
+}
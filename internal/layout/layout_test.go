@@ -0,0 +1,171 @@
+package layout_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/shihanng/gig/internal/file"
+	"github.com/shihanng/gig/internal/layout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGitHubRepo() fstest.MapFS {
+	return fstest.MapFS{
+		"Go.gitignore":                            {Data: []byte("*.exe\n")},
+		"README.md":                               {Data: []byte("# gitignore\n")},
+		"Global/macOS.gitignore":                  {Data: []byte(".DS_Store\n")},
+		"community/Golang/Hugo.gitignore":         {Data: []byte("/public/\n")},
+		"community/Golang/Go.gitignore":           {Data: []byte("shadowed\n")},
+		".github/PULL_REQUEST_TEMPLATE.gitignore": {Data: []byte("hidden\n")},
+	}
+}
+
+func TestGet(t *testing.T) {
+	toptal := fstest.MapFS{"templates/Go.gitignore": {Data: []byte("*.exe\n")}}
+
+	tests := []struct {
+		name      string
+		layout    string
+		fsys      fs.FS
+		want      []string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{name: "auto toptal", layout: layout.Auto, fsys: toptal, want: []string{"go"}, assertion: assert.NoError},
+		{name: "auto github", layout: layout.Auto, fsys: newGitHubRepo(), want: []string{"go", "hugo", "macos"}, assertion: assert.NoError},
+		{name: "github", layout: layout.GitHub, fsys: newGitHubRepo(), want: []string{"go", "hugo", "macos"}, assertion: assert.NoError},
+		{name: "unknown", layout: "svn", fsys: toptal, assertion: assert.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := layout.Get(tt.layout, tt.fsys)
+			tt.assertion(t, err)
+
+			if err != nil {
+				return
+			}
+
+			templates, _, err := l.Templates(tt.fsys)
+			require.NoError(t, err)
+
+			got, err := file.List(templates)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGitHub_Templates(t *testing.T) {
+	l, err := layout.Get(layout.GitHub, nil)
+	require.NoError(t, err)
+
+	templates, categories, err := l.Templates(newGitHubRepo())
+	require.NoError(t, err)
+
+	assert.NoError(t, fstest.TestFS(templates, "Go.gitignore", "Hugo.gitignore", "macOS.gitignore"))
+	assert.Equal(t, map[string]string{
+		"go":    "",
+		"hugo":  "community/Golang",
+		"macos": "Global",
+	}, categories)
+
+	content, err := fs.ReadFile(templates, "Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	orders, err := l.Order(templates)
+	assert.NoError(t, err)
+	assert.Nil(t, orders)
+}
+
+func TestToptal_Templates_NotToptal(t *testing.T) {
+	l, err := layout.Get(layout.Toptal, nil)
+	require.NoError(t, err)
+
+	_, _, err = l.Templates(newGitHubRepo())
+	assert.Error(t, err)
+}
+
+func TestReads(t *testing.T) {
+	files := []string{
+		"templates/Go.gitignore",
+		"templates/order",
+		"Go.gitignore",
+		"README.md",
+		"Global/macOS.gitignore",
+		".github/PULL_REQUEST_TEMPLATE.gitignore",
+	}
+
+	tests := []struct {
+		layout    string
+		want      []string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{layout: layout.Toptal, want: []string{"templates/Go.gitignore", "templates/order"}, assertion: assert.NoError},
+		{
+			layout:    layout.GitHub,
+			want:      []string{"templates/Go.gitignore", "Go.gitignore", "Global/macOS.gitignore"},
+			assertion: assert.NoError,
+		},
+		{
+			layout:    layout.Auto,
+			want:      []string{"templates/Go.gitignore", "templates/order", "Go.gitignore", "Global/macOS.gitignore"},
+			assertion: assert.NoError,
+		},
+		{layout: "svn", assertion: assert.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			reads, err := layout.Reads(tt.layout)
+			tt.assertion(t, err)
+
+			if err != nil {
+				return
+			}
+
+			var got []string
+
+			for _, name := range files {
+				if reads(name) {
+					got = append(got, name)
+				}
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		layout string
+		file   string
+		want   string
+		ok     bool
+	}{
+		{layout: layout.Toptal, file: "templates/Go.gitignore", want: "Go.gitignore", ok: true},
+		{layout: layout.Toptal, file: "templates/Go.patch", want: "Go.patch", ok: true},
+		{layout: layout.Toptal, file: "README.md", ok: false},
+		{layout: layout.GitHub, file: "Go.gitignore", want: "Go.gitignore", ok: true},
+		{layout: layout.GitHub, file: "Global/macOS.gitignore", want: "macOS.gitignore", ok: true},
+		{layout: layout.GitHub, file: ".github/PULL_REQUEST_TEMPLATE.gitignore", ok: false},
+		{layout: layout.GitHub, file: "README.md", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout+"/"+tt.file, func(t *testing.T) {
+			l, err := layout.Get(tt.layout, nil)
+			require.NoError(t, err)
+
+			got, ok := l.Template(tt.file)
+			assert.Equal(t, tt.ok, ok)
+
+			if ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
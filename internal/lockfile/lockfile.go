@@ -0,0 +1,93 @@
+// Package lockfile reads and writes the lockfile that records how
+// a .gitignore file was generated so that it can be reproduced later.
+package lockfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Name is the name of the lockfile written next to .gitignore.
+const Name = ".gig.lock"
+
+// Lock records the templates used to generate a .gitignore file and
+// the commit hash of the templates repository they were taken from.
+// Sources records the commit hashes of the additional named sources.
+// Checksums records the SHA-256 checksums of the template files of
+// the sources, e.g. upstream/Go.gitignore, to detect when their content
+// changes although the commit hashes stay the same.
+type Lock struct {
+	Templates []string          `json:"templates"`
+	Commit    string            `json:"commit"`
+	Sources   map[string]string `json:"sources,omitempty"`
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// Read parses the lockfile in path.
+func Read(path string) (*Lock, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "lockfile: read file")
+	}
+
+	var l Lock
+	if err := json.Unmarshal(content, &l); err != nil {
+		return nil, errors.Wrapf(err, "lockfile: parse %s", path)
+	}
+
+	return &l, nil
+}
+
+// VerifyChecksums compares the checksums recorded in l with checksums,
+// those of the same template files now. It returns an error that lists
+// every file that changed, disappeared, or appeared. A lock without
+// checksums, e.g. written by an older version of gig, is not verified.
+func (l *Lock) VerifyChecksums(checksums map[string]string) error {
+	if len(l.Checksums) == 0 {
+		return nil
+	}
+
+	var problems []string
+
+	for name, sum := range l.Checksums {
+		current, ok := checksums[name]
+
+		switch {
+		case !ok:
+			problems = append(problems, name+" is missing")
+		case current != sum:
+			problems = append(problems, name+" changed")
+		}
+	}
+
+	for name := range checksums {
+		if _, ok := l.Checksums[name]; !ok {
+			problems = append(problems, name+" is new")
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+
+	return errors.Errorf("lockfile: the templates do not match the checksums in %s:\n  %s",
+		Name, strings.Join(problems, "\n  "))
+}
+
+// Write stores l in path.
+func Write(path string, l *Lock) error {
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "lockfile: marshal")
+	}
+
+	content = append(content, '\n')
+
+	return errors.Wrap(ioutil.WriteFile(path, content, 0644), "lockfile: write file") //nolint:gosec,gomnd
+}
@@ -0,0 +1,80 @@
+package lockfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, lockfile.Name)
+
+	_, err = lockfile.Read(path)
+	assert.Error(t, err)
+
+	want := &lockfile.Lock{
+		Templates: []string{"elm", "go"},
+		Commit:    "f0bddaeda3368130d52bde2b62a9df741f6117d4",
+		Sources:   map[string]string{"corp": "0c6ab2cf4b2c4ea2b0e5e8f36f7f9d1a67c3e2d1"},
+		Checksums: map[string]string{"upstream/Go.gitignore": "e15b1b0e0f10012abb6f06891455da689a7a4ff323b70ce46144d8611b7d4d8a"},
+	}
+
+	require.NoError(t, lockfile.Write(path, want))
+
+	got, err := lockfile.Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, lockfile.Name)
+	require.NoError(t, ioutil.WriteFile(path, []byte("templates: go"), 0600))
+
+	_, err = lockfile.Read(path)
+	assert.Error(t, err)
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	l := &lockfile.Lock{
+		Templates: []string{"go"},
+		Checksums: map[string]string{
+			"upstream/Go.gitignore": "aaaa",
+			"upstream/Go.patch":     "bbbb",
+			"upstream/Go.stack":     "cccc",
+		},
+	}
+
+	assert.NoError(t, l.VerifyChecksums(map[string]string{
+		"upstream/Go.gitignore": "aaaa",
+		"upstream/Go.patch":     "bbbb",
+		"upstream/Go.stack":     "cccc",
+	}))
+
+	err := l.VerifyChecksums(map[string]string{
+		"upstream/Go.gitignore": "aaaa",
+		"upstream/Go.patch":     "dddd",
+		"corp/Go.patch":         "eeee",
+	})
+	assert.EqualError(t, err, `lockfile: the templates do not match the checksums in .gig.lock:
+  corp/Go.patch is new
+  upstream/Go.patch changed
+  upstream/Go.stack is missing`)
+
+	// Lockfiles without checksums are not verified.
+	assert.NoError(t, (&lockfile.Lock{Templates: []string{"go"}}).VerifyChecksums(nil))
+}
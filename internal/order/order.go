@@ -4,12 +4,12 @@ package order
 
 import (
 	"bufio"
-	"os"
+	"io/fs"
 
 	"github.com/cockroachdb/errors"
 )
 
-// ReadOrder parses the order find in the provided path and
+// ReadOrder parses the order file name in fsys and
 // returns the order of each items in the file. For the following content file
 //
 //	# A comment
@@ -21,8 +21,8 @@ import (
 //
 //	"go": 0
 //	"elm": 1
-func ReadOrder(path string) (map[string]int, error) {
-	file, err := os.Open(path)
+func ReadOrder(fsys fs.FS, name string) (map[string]int, error) {
+	file, err := fsys.Open(name)
 	if err != nil {
 		return nil, errors.Wrap(err, "order: open file")
 	}
@@ -1,6 +1,7 @@
 package order_test
 
 import (
+	"os"
 	"testing"
 
 	"github.com/shihanng/gig/internal/order"
@@ -9,7 +10,7 @@ import (
 
 func TestReadOrder(t *testing.T) {
 	type args struct {
-		path string
+		name string
 	}
 
 	tests := []struct {
@@ -21,7 +22,7 @@ func TestReadOrder(t *testing.T) {
 		{
 			name: "happy case",
 			args: args{
-				path: `./testdata/order`,
+				name: `order`,
 			},
 			want: map[string]int{
 				"java":          0,
@@ -35,7 +36,7 @@ func TestReadOrder(t *testing.T) {
 		{
 			name: "not found",
 			args: args{
-				path: `./testdata/unknown`,
+				name: `unknown`,
 			},
 			want:      nil,
 			assertion: assert.Error,
@@ -44,7 +45,7 @@ func TestReadOrder(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := order.ReadOrder(tt.args.path)
+			got, err := order.ReadOrder(os.DirFS(`testdata`), tt.args.name)
 			tt.assertion(t, err)
 			assert.Equal(t, tt.want, got)
 		})
@@ -0,0 +1,312 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// withAuth calls fn with the credentials for source. When the server
+// asks for credentials that we do not have or rejects ours, fn is called
+// once more with the credentials of the git credential helper.
+// Credentials in the returned error are redacted.
+func (o *options) withAuth(source string, fn func(auth transport.AuthMethod) error) error {
+	u, ok := httpURL(source)
+	if !ok {
+		return redactError(fn(nil), source)
+	}
+
+	auth := o.auth(u)
+
+	err := fn(auth)
+	if !isAuthError(err) {
+		return redactError(err, source)
+	}
+
+	cred, ok := credentialFill(o.ctx, u)
+	if !ok {
+		return redactError(err, source)
+	}
+
+	err = fn(&http.BasicAuth{Username: cred.username, Password: cred.password})
+
+	switch {
+	case err == nil:
+		credentialApprove(o.ctx, u, cred)
+	case isAuthError(err):
+		credentialReject(o.ctx, u, cred)
+	}
+
+	return redactError(err, source, cred.password)
+}
+
+// auth returns the credentials for u given explicitly or found
+// in the netrc file. It returns nil when there are none so that
+// the credentials in the URL, if any, are used.
+func (o *options) auth(u *url.URL) transport.AuthMethod {
+	if o.password != "" {
+		return &http.BasicAuth{Username: o.username, Password: o.password}
+	}
+
+	if u.User != nil {
+		return nil
+	}
+
+	if login, password, ok := netrcLookup(netrcPath(), u.Hostname()); ok {
+		return &http.BasicAuth{Username: login, Password: password}
+	}
+
+	return nil
+}
+
+func isAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+}
+
+func httpURL(source string) (*url.URL, bool) {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// RedactSource returns source with the password in it, if any, replaced
+// so that it can be shown to the user.
+func RedactSource(source string) string {
+	u, ok := httpURL(source)
+	if !ok {
+		return source
+	}
+
+	return u.Redacted()
+}
+
+// redactError returns err with source and the given secrets removed from its message.
+func redactError(err error, source string, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	redacted := strings.ReplaceAll(msg, source, RedactSource(source))
+
+	if u, ok := httpURL(source); ok && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+	}
+
+	for _, s := range secrets {
+		if s != "" {
+			redacted = strings.ReplaceAll(redacted, s, "xxxxx")
+		}
+	}
+
+	if redacted == msg {
+		return err
+	}
+
+	return &redactedError{msg: redacted, cause: err}
+}
+
+// redactedError has the message of its cause with the secrets removed
+// while errors.Is and errors.As still see the cause.
+type redactedError struct {
+	msg   string
+	cause error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.cause }
+
+func (e *redactedError) Format(s fmt.State, verb rune) { errors.FormatError(e, s, verb) }
+
+// FormatError prints only the redacted message, the details of the cause
+// would contain the secrets again.
+func (e *redactedError) FormatError(p errors.Printer) error {
+	p.Print(e.msg)
+
+	return nil
+}
+
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+
+	return filepath.Join(home, ".netrc")
+}
+
+// netrcLookup returns the login and password for host in the netrc file
+// in path, falling back to the default entry.
+func netrcLookup(path, host string) (string, string, bool) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+
+	type entry struct{ login, password string }
+
+	var (
+		found, fallback *entry
+		current         *entry
+		inMacro         bool
+	)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		// A macro definition ends with an empty line.
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		for i := 0; i < len(fields); i++ {
+			var value string
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+
+			switch fields[i] {
+			case "machine":
+				current = nil
+
+				if value == host && found == nil {
+					found = &entry{}
+					current = found
+				}
+
+				i++
+			case "default":
+				current = nil
+
+				if fallback == nil {
+					fallback = &entry{}
+					current = fallback
+				}
+			case "login":
+				if current != nil {
+					current.login = value
+				}
+
+				i++
+			case "password":
+				if current != nil {
+					current.password = value
+				}
+
+				i++
+			case "account":
+				i++
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+
+	if found == nil {
+		found = fallback
+	}
+
+	if found == nil || found.password == "" {
+		return "", "", false
+	}
+
+	return found.login, found.password, true
+}
+
+type credential struct {
+	username string
+	password string
+}
+
+// credentialFill asks the git credential helpers for the credentials of u.
+// Git never prompts in the terminal for them.
+func credentialFill(ctx context.Context, u *url.URL) (credential, bool) {
+	out, err := runCredential(ctx, "fill", u, credential{})
+	if err != nil {
+		return credential{}, false
+	}
+
+	var cred credential
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		const n = 2
+
+		kv := strings.SplitN(scanner.Text(), "=", n)
+		if len(kv) != n {
+			continue
+		}
+
+		switch kv[0] {
+		case "username":
+			cred.username = kv[1]
+		case "password":
+			cred.password = kv[1]
+		}
+	}
+
+	return cred, cred.password != ""
+}
+
+func credentialApprove(ctx context.Context, u *url.URL, cred credential) {
+	_, _ = runCredential(ctx, "approve", u, cred)
+}
+
+func credentialReject(ctx context.Context, u *url.URL, cred credential) {
+	_, _ = runCredential(ctx, "reject", u, cred)
+}
+
+func runCredential(ctx context.Context, action string, u *url.URL, cred credential) ([]byte, error) {
+	var in bytes.Buffer
+
+	fmt.Fprintf(&in, "protocol=%s\nhost=%s\npath=%s\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"))
+
+	if cred.username != "" {
+		fmt.Fprintf(&in, "username=%s\n", cred.username)
+	} else if u.User != nil {
+		fmt.Fprintf(&in, "username=%s\n", u.User.Username())
+	}
+
+	if cred.password != "" {
+		fmt.Fprintf(&in, "password=%s\n", cred.password)
+	}
+
+	var out bytes.Buffer
+
+	cmd := exec.Command("git", "credential", action)
+	cmd.Stdin = &in
+	cmd.Stdout = &out
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+
+	err := run(ctx, cmd)
+
+	return out.Bytes(), errors.Wrapf(err, "repo: git credential %s", action)
+}
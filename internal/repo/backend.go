@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os/exec"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// The git backends that can be given to WithBackend.
+const (
+	// GoGitBackend clones and fetches the whole history with go-git,
+	// which cannot fetch into shallow clones.
+	GoGitBackend = "go-git"
+	// GitBackend runs the git binary, which clones partially: the files
+	// of a commit are only fetched when the commit is checked out.
+	// The clone is shallow unless WithShallow(false) is given.
+	GitBackend = "git"
+	// AutoBackend is GitBackend when git is installed and GoGitBackend otherwise.
+	AutoBackend = "auto"
+)
+
+// backend performs the git operations on the cache that depend on how
+// the repository was cloned. Both backends store a bare repository in
+// the same format, so opening it and resolving revisions, which only read
+// references and commits, are done with go-git for both.
+type backend interface {
+	// clone clones source into the directory path as a bare repository
+	// with a branch tracking the default branch of the remote.
+	clone(path, source string, o *options) error
+	// fetch fetches the latest commits from the remote of r.
+	fetch(r *git.Repository, source string, o *options) error
+	// checkout returns the files of the commit hash of r.
+	checkout(r *git.Repository, hash plumbing.Hash, o *options) (fs.FS, error)
+	// prune deletes the unreachable objects of r and repacks the others.
+	prune(r *git.Repository, o *options) error
+}
+
+// CheckBackend returns an error when name is not one of the backends.
+func CheckBackend(name string) error {
+	switch name {
+	case GoGitBackend, GitBackend, AutoBackend:
+		return nil
+	default:
+		return errors.Errorf("repo: unknown git backend %s, use %s, %s, or %s", name, AutoBackend, GoGitBackend, GitBackend)
+	}
+}
+
+// backend returns the backend given with WithBackend.
+func (o *options) backend() (backend, error) {
+	if err := CheckBackend(o.backendName); err != nil {
+		return nil, err
+	}
+
+	switch o.backendName {
+	case GitBackend:
+		return gitBackend{}, nil
+	case AutoBackend:
+		if _, err := exec.LookPath("git"); err == nil {
+			return gitBackend{}, nil
+		}
+	}
+
+	return goGitBackend{}, nil
+}
+
+// backendFor is like backend but always returns the git backend for
+// a partial clone, which go-git cannot read all files of, and for
+// a shallow clone, which go-git cannot fetch into.
+func (o *options) backendFor(r *git.Repository) (backend, error) {
+	if isPartial(r) || IsShallow(r) {
+		return gitBackend{}, nil
+	}
+
+	return o.backend()
+}
+
+// IsShallow reports whether r is a shallow clone without the whole history.
+func IsShallow(r *git.Repository) bool {
+	shallow, err := r.Storer.Shallow()
+
+	return err == nil && len(shallow) > 0
+}
+
+func isPartial(r *git.Repository) bool {
+	cfg, err := r.Config()
+	if err != nil {
+		return false
+	}
+
+	return cfg.Raw.Section("remote").Subsection(git.DefaultRemoteName).Option("promisor") == "true"
+}
+
+// goGitBackend clones the whole history with go-git.
+type goGitBackend struct{}
+
+func (goGitBackend) clone(path, source string, o *options) error {
+	return o.withAuth(source, func(auth transport.AuthMethod) error {
+		_, err := git.PlainCloneContext(o.ctx, path, true, &git.CloneOptions{
+			URL:      source,
+			Auth:     auth,
+			Progress: ioutil.Discard,
+		})
+
+		return contextError(o.ctx, err)
+	})
+}
+
+func (goGitBackend) fetch(r *git.Repository, source string, o *options) error {
+	return o.withAuth(source, func(auth transport.AuthMethod) error {
+		err := r.FetchContext(o.ctx, &git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			Auth:       auth,
+			Progress:   ioutil.Discard,
+		})
+
+		return contextError(o.ctx, err)
+	})
+}
+
+// contextError returns the error of ctx instead of err when ctx is done
+// because go-git does not keep it in the errors of interrupted transfers.
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func (goGitBackend) checkout(r *git.Repository, hash plumbing.Hash, o *options) (fs.FS, error) {
+	tree, err := commitTree(r, hash.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return TreeFS(tree), nil
+}
+
+func (goGitBackend) prune(r *git.Repository, o *options) error {
+	if err := r.Prune(git.PruneOptions{Handler: r.DeleteObject}); err != nil {
+		return errors.Wrap(err, "repo: prune objects")
+	}
+
+	return errors.Wrap(r.RepackObjects(&git.RepackConfig{}), "repo: repack objects")
+}
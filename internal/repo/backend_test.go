@@ -0,0 +1,260 @@
+package repo_test
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitBackend(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+	first := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	// Local sources only filter the clone when served through file://.
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowFilter", "true").Run())
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowAnySHA1InWant", "true").Run())
+
+	url := "file://" + filepath.ToSlash(source.dir)
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache")
+	backend := repo.WithBackend(repo.GitBackend)
+
+	r, err := repo.New(path, url, backend)
+	require.NoError(t, err)
+
+	promisor, err := exec.Command("git", "-C", path, "config", "remote.origin.promisor").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "true\n", string(promisor), "partial clone")
+
+	hash, fsys, err := repo.Checkout(r, "", backend)
+	require.NoError(t, err)
+	assert.Equal(t, first, hash)
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	second := source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	old, current, err := repo.Update(r, backend)
+	require.NoError(t, err)
+	assert.Equal(t, first, old)
+	assert.Equal(t, second, current)
+
+	changes, err := repo.Diff(r, old, current)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.Change{{Action: "modified", Path: "templates/Go.gitignore"}}, changes)
+
+	// The files of a partial clone that were never checked out are not a problem.
+	problems, err := repo.Check(path, "templates")
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	// The partial clone is checked out with git even when go-git is asked for.
+	r, err = repo.Open(path)
+	require.NoError(t, err)
+
+	_, fsys, err = repo.Checkout(r, second, repo.WithBackend(repo.GoGitBackend))
+	require.NoError(t, err)
+
+	content, err = fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.test\n", string(content))
+
+	assert.NoError(t, repo.Prune(r))
+}
+
+func TestCheckBackend(t *testing.T) {
+	assert.NoError(t, repo.CheckBackend(repo.GoGitBackend))
+	assert.NoError(t, repo.CheckBackend(repo.GitBackend))
+	assert.NoError(t, repo.CheckBackend(repo.AutoBackend))
+	assert.Error(t, repo.CheckBackend("svn"))
+}
+
+func TestGitBackend_Shallow(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+	first := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+	second := source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowFilter", "true").Run())
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowAnySHA1InWant", "true").Run())
+
+	url := "file://" + filepath.ToSlash(source.dir)
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	full, err := repo.New(filepath.Join(dir, "full"), url, repo.WithBackend(repo.GitBackend), repo.WithShallow(false))
+	require.NoError(t, err)
+	assert.False(t, repo.IsShallow(full))
+
+	path := filepath.Join(dir, "cache")
+	backend := repo.WithBackend(repo.GitBackend)
+
+	r, err := repo.New(path, url, backend)
+	require.NoError(t, err)
+	assert.True(t, repo.IsShallow(r))
+
+	info, err := repo.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.Shallow)
+	assert.True(t, info.Partial)
+	assert.Equal(t, 1, info.Commits)
+	assert.Equal(t, 1, info.Missing)
+
+	hash, _, err := repo.Checkout(r, "", backend)
+	require.NoError(t, err)
+	assert.Equal(t, second, hash)
+
+	_, _, err = repo.Checkout(r, first, backend)
+	assert.Error(t, err, "older commits are not cloned")
+
+	_, err = repo.ExportBundle(ioutil.Discard, r, "", backend)
+	assert.Error(t, err, "a bundle needs the whole history")
+
+	require.NoError(t, repo.Deepen(r, backend))
+	assert.False(t, repo.IsShallow(r))
+
+	hash, fsys, err := repo.Checkout(r, first, backend)
+	require.NoError(t, err)
+	assert.Equal(t, first, hash)
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	info, err = repo.Stat(path)
+	require.NoError(t, err)
+	assert.False(t, info.Shallow)
+	assert.Equal(t, 2, info.Commits)
+
+	hash, err = repo.ExportBundle(ioutil.Discard, r, "", backend)
+	assert.NoError(t, err)
+	assert.Equal(t, second, hash)
+}
+
+func TestGitBackend_Sparse(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+	source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"README.md":              "# templates\n",
+		"docs/usage.md":          "# usage\n",
+	})
+
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowFilter", "true").Run())
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowAnySHA1InWant", "true").Run())
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache")
+	backend := repo.WithBackend(repo.GitBackend)
+
+	r, err := repo.New(path, "file://"+filepath.ToSlash(source.dir), backend)
+	require.NoError(t, err)
+
+	sparse := repo.WithSparse(func(name string) bool { return strings.HasPrefix(name, "templates/") })
+
+	_, fsys, err := repo.Checkout(r, "", backend, sparse)
+	require.NoError(t, err)
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	info, err := repo.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Missing, "only the templates are fetched")
+	assert.Positive(t, info.Saved)
+
+	_, fsys, err = repo.Checkout(r, "", backend)
+	require.NoError(t, err)
+
+	content, err = fs.ReadFile(fsys, "README.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "# templates\n", string(content))
+
+	info, err = repo.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, 0, info.Missing)
+	assert.Zero(t, info.Saved)
+}
+
+func TestGitBackend_Offline(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowFilter", "true").Run())
+	require.NoError(t, exec.Command("git", "-C", source.dir, "config", "uploadpack.allowAnySHA1InWant", "true").Run())
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := repo.WithBackend(repo.GitBackend)
+	offline := repo.WithOffline(true)
+
+	r, err := repo.New(filepath.Join(dir, "cache"), "file://"+filepath.ToSlash(source.dir),
+		backend, repo.WithShallow(false))
+	require.NoError(t, err)
+
+	_, _, err = repo.Checkout(r, "", backend)
+	require.NoError(t, err)
+
+	second := source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	_, _, err = repo.Update(r, backend)
+	require.NoError(t, err)
+
+	// The files of the fetched commit are left out of the partial clone.
+	_, _, err = repo.Checkout(r, "", backend, offline)
+	assert.ErrorIs(t, err, repo.ErrOffline)
+
+	_, err = repo.ExportBundle(ioutil.Discard, r, "", backend, offline)
+	assert.ErrorIs(t, err, repo.ErrOffline)
+
+	hash, _, err := repo.Checkout(r, "", backend)
+	require.NoError(t, err)
+	assert.Equal(t, second, hash)
+
+	_, fsys, err := repo.Checkout(r, "", backend, offline)
+	require.NoError(t, err)
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.test\n", string(content))
+}
@@ -0,0 +1,272 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+)
+
+const (
+	bundleV2Header = "# v2 git bundle"
+	bundleV3Header = "# v3 git bundle"
+	packWindow     = 10
+)
+
+// IsBundle reports whether the content starting with head is a git bundle.
+func IsBundle(head []byte) bool {
+	return strings.HasPrefix(string(head), bundleV2Header) || strings.HasPrefix(string(head), bundleV3Header)
+}
+
+// ExportBundle writes the commit rev of r, see Checkout, together with its history
+// to w as a git bundle, which can be imported with ImportBundle or cloned with git.
+// It returns the commit hash of rev. The files missing in a partial clone
+// are fetched first while a shallow clone has to be deepened, see Deepen.
+func ExportBundle(w io.Writer, r *git.Repository, rev string, opts ...Option) (string, error) {
+	if IsShallow(r) {
+		return "", errors.New("repo: a bundle needs the whole history, which a shallow clone does not have, see Deepen")
+	}
+
+	o := newOptions(opts)
+
+	l, err := o.lockRepo(r, false)
+	if err != nil {
+		return "", err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	branchName := plumbing.Master.Short()
+	if branch, err := trackingBranch(r); err == nil {
+		branchName = branch.Name
+	}
+
+	if rev == "" {
+		rev = string(plumbing.NewBranchReferenceName(branchName))
+	}
+
+	hash, err := Resolve(r, rev)
+	if err != nil {
+		return "", err
+	}
+
+	if isPartial(r) {
+		if err := (gitBackend{}).fetchMissing(r, hash, true, o); err != nil {
+			return "", err
+		}
+	}
+
+	objects, err := revlist.Objects(r.Storer, []plumbing.Hash{hash}, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "repo: list objects")
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\n%s %s\n%s %s\n\n", bundleV2Header,
+		hash, plumbing.NewBranchReferenceName(branchName), hash, plumbing.HEAD); err != nil {
+		return "", errors.Wrap(err, "repo: write bundle header")
+	}
+
+	if _, err := packfile.NewEncoder(w, r.Storer, false).Encode(objects, packWindow); err != nil {
+		return "", errors.Wrap(err, "repo: write bundle packfile")
+	}
+
+	return hash.String(), nil
+}
+
+// ImportBundle initializes or updates the repository cached in path with
+// the objects of the git bundle and moves the branch tracking the remote
+// to the branch of the bundle. A new repository gets source as its remote
+// so that it can be fetched later. It returns the commit hash of the branch.
+func ImportBundle(path, source string, bundle io.Reader, opts ...Option) (string, error) {
+	br := bufio.NewReader(bundle)
+
+	head, err := readBundleHeader(br)
+	if err != nil {
+		return "", err
+	}
+
+	o := newOptions(opts)
+
+	l, err := o.lock(path, true)
+	if err != nil {
+		return "", err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	r, err := Open(path)
+	if err == nil {
+		return importPack(r, head, br, o)
+	}
+
+	if !errors.Is(err, ErrNotCached) {
+		return "", err
+	}
+
+	parent, base := filepath.Split(filepath.Clean(path))
+
+	tmp, err := ioutil.TempDir(parent, "."+base+".import-")
+	if err != nil {
+		return "", errors.Wrap(err, "repo: create temporary directory")
+	}
+
+	defer os.RemoveAll(tmp)
+
+	if r, err = initCache(tmp, source, head.Name().Short()); err != nil {
+		return "", err
+	}
+
+	hash, err := importPack(r, head, br, o)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, replaceCache(path, tmp)
+}
+
+// readBundleHeader reads the header of a git bundle up to its packfile
+// and returns its first branch.
+func readBundleHeader(br *bufio.Reader) (*plumbing.Reference, error) {
+	signature, err := br.ReadString('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: read bundle header")
+	}
+
+	signature = strings.TrimSuffix(signature, "\n")
+	if signature != bundleV2Header && signature != bundleV3Header {
+		return nil, errors.New("repo: not a git bundle")
+	}
+
+	var head *plumbing.Reference
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, errors.Wrap(err, "repo: read bundle header")
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if head == nil {
+				return nil, errors.New("repo: no branch found in bundle")
+			}
+
+			return head, nil
+		case strings.HasPrefix(line, "@"):
+			if strings.HasPrefix(line, "@object-format=") && line != "@object-format=sha1" {
+				return nil, errors.Errorf("repo: unsupported bundle capability %s", line)
+			}
+		case strings.HasPrefix(line, "-"):
+			return nil, errors.New("repo: bundles that require other commits are not supported, create one with the whole history")
+		default:
+			fields := strings.SplitN(line, " ", 2) //nolint:gomnd
+			if len(fields) != 2 || !plumbing.IsHash(fields[0]) {
+				return nil, errors.Errorf("repo: invalid bundle reference %s", line)
+			}
+
+			name := plumbing.ReferenceName(fields[1])
+			if head == nil && name.IsBranch() {
+				head = plumbing.NewHashReference(name, plumbing.NewHash(fields[0]))
+			}
+		}
+	}
+}
+
+// initCache initializes a bare repository in path with source as its remote
+// and a branch that tracks the branch of the remote.
+func initCache(path, source, branch string) (*git.Repository, error) {
+	r, err := git.PlainInit(path, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: init repository")
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: get config")
+	}
+
+	cfg.Remotes[git.DefaultRemoteName] = &config.RemoteConfig{
+		Name:  git.DefaultRemoteName,
+		URLs:  []string{source},
+		Fetch: []config.RefSpec{config.RefSpec(fmt.Sprintf(config.DefaultFetchRefSpec, git.DefaultRemoteName))},
+	}
+	cfg.Branches[branch] = &config.Branch{
+		Name:   branch,
+		Remote: git.DefaultRemoteName,
+		Merge:  plumbing.NewBranchReferenceName(branch),
+	}
+
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, errors.Wrap(err, "repo: set config")
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))
+
+	return r, errors.Wrap(r.Storer.SetReference(head), "repo: set HEAD")
+}
+
+// importPack stores the packfile pack in r and points the branch tracking
+// the remote, and its remote branch, to the commit of head unless
+// it is not signed by a trusted key, see WithTrustedKeys.
+func importPack(r *git.Repository, head *plumbing.Reference, pack io.Reader, o *options) (string, error) {
+	if err := packfile.UpdateObjectStorage(r.Storer, pack); err != nil {
+		return "", errors.Wrap(err, "repo: store bundle objects")
+	}
+
+	if _, err := r.CommitObject(head.Hash()); err != nil {
+		return "", errors.Wrapf(err, "repo: get commit %s of bundle", head.Hash())
+	}
+
+	if _, err := o.verify(r, head.Hash().String()); err != nil {
+		return "", err
+	}
+
+	branch, err := trackingBranch(r)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(branch.Name),
+		plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short()),
+	} {
+		if err := r.Storer.SetReference(plumbing.NewHashReference(name, head.Hash())); err != nil {
+			return "", errors.Wrap(err, "repo: set branch reference")
+		}
+	}
+
+	if err := recordFetch(r); err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
+}
+
+// replaceCache moves the cache prepared in tmp to path, replacing
+// whatever is cached in path.
+func replaceCache(path, tmp string) error {
+	old := fmt.Sprintf("%s.old-%d", filepath.Clean(path), time.Now().UnixNano())
+
+	if err := os.Rename(path, old); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "repo: move old cache aside")
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(err, "repo: move new cache into place")
+	}
+
+	return errors.Wrap(os.RemoveAll(old), "repo: remove old cache")
+}
@@ -0,0 +1,132 @@
+package repo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundle(t *testing.T) {
+	source := newSourceRepo(t)
+	first := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	connected, err := repo.New(filepath.Join(dir, "connected"), source.dir)
+	require.NoError(t, err)
+
+	var bundle bytes.Buffer
+
+	got, err := repo.ExportBundle(&bundle, connected, "")
+	require.NoError(t, err)
+	assert.Equal(t, first, got)
+	assert.True(t, repo.IsBundle(bundle.Bytes()))
+
+	// Initialize a new cache from the bundle.
+	path := filepath.Join(dir, "airgapped")
+
+	got, err = repo.ImportBundle(path, source.dir, &bundle)
+	require.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	r, err := repo.Open(path)
+	require.NoError(t, err)
+
+	got, _, err = repo.Checkout(r, "")
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	info, err := repo.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, source.dir, info.Remote)
+	assert.NotNil(t, info.LastFetch)
+
+	// Refresh the cache with a newer bundle.
+	second := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n*.test\n"})
+
+	_, _, err = repo.Update(connected)
+	require.NoError(t, err)
+
+	bundle.Reset()
+
+	_, err = repo.ExportBundle(&bundle, connected, "")
+	require.NoError(t, err)
+
+	got, err = repo.ImportBundle(path, source.dir, &bundle)
+	require.NoError(t, err)
+	assert.Equal(t, second, got)
+
+	r, err = repo.Open(path)
+	require.NoError(t, err)
+
+	got, _, err = repo.Checkout(r, "")
+	assert.NoError(t, err)
+	assert.Equal(t, second, got)
+
+	got, _, err = repo.Checkout(r, first)
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+}
+
+func TestImportBundle_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "templates.bundle")
+
+	cmd := exec.Command("git", "bundle", "create", file, "--all")
+	cmd.Dir = source.dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	bundle, err := os.Open(file)
+	require.NoError(t, err)
+
+	defer bundle.Close()
+
+	got, err := repo.ImportBundle(filepath.Join(dir, "cache"), source.dir, bundle)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestImportBundle_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		bundle string
+	}{
+		{name: "not a bundle", bundle: "PK\x03\x04"},
+		{name: "prerequisites", bundle: "# v2 git bundle\n-0123456789012345678901234567890123456789\n"},
+		{name: "no branch", bundle: "# v2 git bundle\n0123456789012345678901234567890123456789 refs/tags/v1\n\nPACK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gig")
+			require.NoError(t, err)
+
+			defer os.RemoveAll(dir)
+
+			_, err = repo.ImportBundle(filepath.Join(dir, "cache"), repo.SourceRepo, bytes.NewBufferString(tt.bundle))
+			assert.Error(t, err)
+		})
+	}
+}
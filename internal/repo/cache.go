@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Info describes the repository or the snapshot cached in Path.
+// For a snapshot, LastFetch is the time it was created.
+// Commits is the number of commits cached, which is 1 for a shallow clone
+// that was not updated yet. A partial clone only has the files of the commits
+// that were checked out, Missing is the number of files of the cached commits
+// that were left out. Saved estimates the size in bytes that the missing files
+// would take in the cache from the average size of the cached ones.
+type Info struct {
+	Path      string     `json:"path"`
+	Size      int64      `json:"size"`
+	Commit    string     `json:"commit"`
+	Remote    string     `json:"remote"`
+	LastFetch *time.Time `json:"last_fetch"`
+	Snapshot  bool       `json:"snapshot"`
+	Shallow   bool       `json:"shallow"`
+	Partial   bool       `json:"partial"`
+	Commits   int        `json:"commits"`
+	Missing   int        `json:"missing"`
+	Saved     int64      `json:"saved"`
+}
+
+// Stat returns information about the repository or the snapshot cached
+// in path without accessing the network. It returns ErrNotCached when
+// there is nothing cached in path.
+func Stat(path string, opts ...Option) (*Info, error) {
+	size, err := Size(path)
+	if os.IsNotExist(errors.UnwrapAll(err)) {
+		return nil, ErrNotCached
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := Info{Path: path, Size: size}
+
+	if s, _, err := OpenSnapshot(path); !errors.Is(err, ErrNotCached) {
+		if err != nil {
+			return nil, err
+		}
+
+		info.Commit = s.Commit
+		info.Remote = s.Source
+		info.LastFetch = &s.Created
+		info.Snapshot = true
+
+		return &info, nil
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if branch, err := trackingBranch(r); err == nil {
+		ref, err := r.Reference(plumbing.NewBranchReferenceName(branch.Name), true)
+		if err != nil {
+			return nil, errors.Wrap(err, "repo: get branch reference")
+		}
+
+		info.Commit = ref.Hash().String()
+	}
+
+	if remote, err := r.Remote(git.DefaultRemoteName); err == nil && len(remote.Config().URLs) > 0 {
+		info.Remote = RedactSource(remote.Config().URLs[0])
+	}
+
+	lastFetch, err := LastFetch(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !lastFetch.IsZero() {
+		info.LastFetch = &lastFetch
+	}
+
+	info.Shallow = IsShallow(r)
+	info.Partial = isPartial(r)
+
+	if info.Partial {
+		path, err := gitDir(r)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := newOptions(opts).ctx
+
+		missing, err := missingObjects(ctx, path, "--all")
+		if err != nil {
+			return nil, err
+		}
+
+		info.Missing = len(missing)
+
+		if info.Saved, err = missingSize(ctx, path, info.Missing); err != nil {
+			return nil, err
+		}
+	}
+
+	commits, err := r.CommitObjects()
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: list commits")
+	}
+
+	err = commits.ForEach(func(*object.Commit) error {
+		info.Commits++
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: count commits")
+	}
+
+	return &info, nil
+}
+
+// missingSize estimates the size in bytes that missing files would take
+// in the partial clone in path from the average size of the files in it.
+func missingSize(ctx context.Context, path string, missing int) (int64, error) {
+	if missing == 0 {
+		return 0, nil
+	}
+
+	out, err := runGit(ctx, path, nil, nil, "cat-file", "--batch-all-objects",
+		"--batch-check=%(objecttype) %(objectsize:disk)")
+	if err != nil {
+		return 0, err
+	}
+
+	var size, files int64
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var (
+			kind string
+			n    int64
+		)
+
+		if _, err := fmt.Sscan(scanner.Text(), &kind, &n); err != nil {
+			return 0, errors.Wrap(err, "repo: read object size")
+		}
+
+		if kind == "blob" {
+			size += n
+			files++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Wrap(err, "repo: read object sizes")
+	}
+
+	if files == 0 {
+		return 0, nil
+	}
+
+	return size / files * int64(missing), nil
+}
+
+// Size returns the total size in bytes of the files in path.
+func Size(path string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, errors.Wrap(err, "repo: get cache size")
+}
+
+// Prune deletes the objects of r that are no longer reachable from any reference
+// and repacks the remaining ones into a single pack.
+// The cache has to be opened again to read objects afterwards.
+func Prune(r *git.Repository, opts ...Option) error {
+	o := newOptions(opts)
+
+	b, err := o.backendFor(r)
+	if err != nil {
+		return err
+	}
+
+	l, err := o.lockRepo(r, true)
+	if err != nil {
+		return err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	return b.prune(r, o)
+}
+
+// Clean deletes the repository cached in path together with the broken caches
+// that were moved aside by Repair. It returns the deleted directories.
+func Clean(path string, opts ...Option) ([]string, error) {
+	l, err := newOptions(opts).lock(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	broken, err := filepath.Glob(filepath.Clean(path) + ".broken-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: find broken caches")
+	}
+
+	var removed []string
+
+	for _, dir := range append([]string{path}, broken...) {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, errors.Wrap(err, "repo: remove cache")
+		}
+
+		removed = append(removed, dir)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,101 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStat(t *testing.T) {
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache")
+
+	_, err = repo.Stat(path)
+	assert.ErrorIs(t, err, repo.ErrNotCached)
+
+	_, err = repo.New(path, source.dir)
+	require.NoError(t, err)
+
+	info, err := repo.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, info.Path)
+	assert.Equal(t, want, info.Commit)
+	assert.Equal(t, source.dir, info.Remote)
+	assert.Positive(t, info.Size)
+	assert.NotNil(t, info.LastFetch)
+}
+
+func TestPrune(t *testing.T) {
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	r, err := repo.New(dir, source.dir)
+	require.NoError(t, err)
+
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+
+	w, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = w.Write([]byte("unreachable"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	unreachable, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	assert.NoError(t, repo.Prune(r))
+	assert.Error(t, r.Storer.HasEncodedObject(unreachable))
+
+	r, err = repo.Open(dir)
+	require.NoError(t, err)
+
+	got, _, err := repo.Checkout(r, "")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestClean(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache")
+	broken := path + ".broken-20200102030405"
+
+	_, err = repo.New(path, source.dir)
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(broken, 0700))
+
+	removed, err := repo.Clean(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path, broken}, removed)
+	assert.NoDirExists(t, path)
+	assert.NoDirExists(t, broken)
+
+	removed, err = repo.Clean(path)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+}
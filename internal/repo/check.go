@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+)
+
+// Check inspects the repository cached in path and returns a description
+// of every problem found, e.g. after an interrupted clone. Every file in dir
+// and its subdirectories of the default revision is read to detect missing
+// or corrupted objects, apart from the files of a partial clone.
+// It returns ErrNotCached when there is nothing cached in path.
+func Check(path, dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(path)
+	if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+		return nil, ErrNotCached
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: read cache directory")
+	}
+
+	// Snapshots are plain files and cannot be repaired, only replaced.
+	if _, _, err := OpenSnapshot(path); !errors.Is(err, ErrNotCached) {
+		if err != nil {
+			return []string{fmt.Sprintf("invalid snapshot: %v", err)}, nil
+		}
+
+		return nil, nil
+	}
+
+	r, err := git.PlainOpen(path)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return []string{fmt.Sprintf("%s is not a git repository", path)}, nil
+	}
+
+	if err != nil {
+		return []string{fmt.Sprintf("cannot open repository: %v", err)}, nil
+	}
+
+	if _, err := r.Head(); err != nil {
+		return []string{fmt.Sprintf("HEAD is missing or invalid: %v", err)}, nil
+	}
+
+	// The files of a partial clone are only fetched when they are checked out,
+	// so only its commits and directories are always there.
+	partial := isPartial(r)
+
+	_, fsys, err := newOptions(nil).checkout(r, "", goGitBackend{})
+	if err != nil {
+		return []string{fmt.Sprintf("default revision is missing: %v", err)}, nil
+	}
+
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil || len(files) == 0 {
+		return []string{fmt.Sprintf("%s directory is missing or empty", dir)}, nil
+	}
+
+	var problems []string
+
+	err = fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is missing or corrupted: %v", name, err))
+
+			return nil
+		}
+
+		if d.IsDir() || partial {
+			return nil
+		}
+
+		if _, err := fs.ReadFile(fsys, name); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is missing or corrupted: %v", name, err))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: check files")
+	}
+
+	return problems, nil
+}
+
+// Repair moves the cache in path aside and clones repoSource into path again.
+// It returns where the broken cache was moved to.
+func Repair(path, repoSource string, opts ...Option) (string, *git.Repository, error) {
+	o := newOptions(opts)
+
+	l, err := o.lock(path, true)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	var aside string
+
+	if _, err := os.Stat(path); err == nil {
+		aside = fmt.Sprintf("%s.broken-%s", path, time.Now().Format("20060102150405"))
+
+		if err := os.Rename(path, aside); err != nil {
+			return "", nil, errors.Wrap(err, "repo: move broken cache aside")
+		}
+	}
+
+	r, err := clone(path, repoSource, o)
+
+	return aside, r, err
+}
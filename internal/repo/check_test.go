@@ -0,0 +1,118 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	empty := newSourceRepo(t)
+	empty.commit(map[string]string{"README.md": "no templates\n"})
+
+	tests := []struct {
+		name     string
+		source   string
+		breakFn  func(t *testing.T, path string)
+		problems int
+	}{
+		{
+			name:     "healthy",
+			source:   source.dir,
+			breakFn:  func(t *testing.T, path string) {},
+			problems: 0,
+		},
+		{
+			name:   "missing HEAD",
+			source: source.dir,
+			breakFn: func(t *testing.T, path string) {
+				require.NoError(t, os.Remove(filepath.Join(path, "HEAD")))
+			},
+			problems: 1,
+		},
+		{
+			name:   "missing objects",
+			source: source.dir,
+			breakFn: func(t *testing.T, path string) {
+				require.NoError(t, os.RemoveAll(filepath.Join(path, "objects")))
+				require.NoError(t, os.Mkdir(filepath.Join(path, "objects"), 0700))
+			},
+			problems: 1,
+		},
+		{
+			name:     "no templates",
+			source:   empty.dir,
+			breakFn:  func(t *testing.T, path string) {},
+			problems: 1,
+		},
+		{
+			name:   "not a repository",
+			source: source.dir,
+			breakFn: func(t *testing.T, path string) {
+				require.NoError(t, os.RemoveAll(path))
+				require.NoError(t, os.Mkdir(path, 0700))
+				require.NoError(t, ioutil.WriteFile(filepath.Join(path, "config"), nil, 0600))
+			},
+			problems: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gig")
+			require.NoError(t, err)
+
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "cache")
+
+			_, err = repo.Check(path, "templates")
+			assert.ErrorIs(t, err, repo.ErrNotCached)
+
+			_, err = repo.New(path, tt.source)
+			require.NoError(t, err)
+
+			tt.breakFn(t, path)
+
+			problems, err := repo.Check(path, "templates")
+			assert.NoError(t, err)
+			assert.Len(t, problems, tt.problems, problems)
+		})
+	}
+}
+
+func TestRepair(t *testing.T) {
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache")
+
+	_, err = repo.New(path, source.dir)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(path, "HEAD")))
+
+	aside, r, err := repo.Repair(path, source.dir)
+	require.NoError(t, err)
+	assert.DirExists(t, aside)
+
+	got, _, err := repo.Checkout(r, "")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	problems, err := repo.Check(path, "templates")
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
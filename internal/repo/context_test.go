@@ -0,0 +1,138 @@
+package repo_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Canceled(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	for _, backend := range []string{repo.GoGitBackend, repo.GitBackend} {
+		backend := backend
+
+		t.Run(backend, func(t *testing.T) {
+			if _, err := exec.LookPath("git"); err != nil && backend == repo.GitBackend {
+				t.Skip("git is not installed")
+			}
+
+			dir, err := ioutil.TempDir("", "gig")
+			require.NoError(t, err)
+
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "cache")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err = repo.New(path, "file://"+filepath.ToSlash(source.dir),
+				repo.WithContext(ctx), repo.WithBackend(backend))
+			assert.ErrorIs(t, err, context.Canceled)
+
+			assert.Equal(t, []string{"cache.lock"}, dirNames(t, dir), "nothing but the lock file is left behind")
+
+			_, err = repo.Open(path)
+			assert.ErrorIs(t, err, repo.ErrNotCached)
+		})
+	}
+}
+
+func TestNew_CanceledMidTransfer(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	backend := &cgi.Handler{
+		Path: git,
+		Args: []string{"http-backend"},
+		Env: []string{
+			"GIT_PROJECT_ROOT=" + filepath.Dir(source.dir),
+			"GIT_HTTP_EXPORT_ALL=1",
+		},
+	}
+
+	transfer := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	// The references are advertised but the pack never comes.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			backend.ServeHTTP(w, r)
+
+			return
+		}
+
+		select {
+		case transfer <- struct{}{}:
+		default:
+		}
+
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+
+	defer srv.Close()
+	defer close(release)
+
+	url := srv.URL + "/" + filepath.Base(source.dir)
+
+	for _, backend := range []string{repo.GoGitBackend, repo.GitBackend} {
+		backend := backend
+
+		t.Run(backend, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gig")
+			require.NoError(t, err)
+
+			defer os.RemoveAll(dir)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go func() {
+				<-transfer
+
+				// The clone is running in its temporary directory.
+				assert.Len(t, dirNames(t, dir), 2)
+				cancel()
+			}()
+
+			_, err = repo.New(filepath.Join(dir, "cache"), url, repo.WithContext(ctx), repo.WithBackend(backend))
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, []string{"cache.lock"}, dirNames(t, dir), "the temporary directory is removed")
+		})
+	}
+}
+
+// dirNames returns the names of the entries of dir.
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	return names
+}
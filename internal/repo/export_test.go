@@ -0,0 +1,4 @@
+package repo
+
+// RedactError is exported for the tests of redactError.
+var RedactError = redactError //nolint:gochecknoglobals
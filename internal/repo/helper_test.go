@@ -0,0 +1,71 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceRepo is a local templates repository used as the source
+// of the tests that do not require internet access.
+type sourceRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+
+	// signKey signs the commits when set.
+	signKey *openpgp.Entity
+}
+
+func newSourceRepo(t *testing.T) *sourceRepo {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gig-source")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	return &sourceRepo{t: t, dir: dir, repo: r}
+}
+
+// commit writes files (path to content) into the repository and commits them.
+func (s *sourceRepo) commit(files map[string]string) string {
+	s.t.Helper()
+
+	return s.commitAt(time.Now(), files)
+}
+
+// commitAt is like commit but with the given commit time.
+func (s *sourceRepo) commitAt(when time.Time, files map[string]string) string {
+	s.t.Helper()
+
+	wt, err := s.repo.Worktree()
+	require.NoError(s.t, err)
+
+	for path, content := range files {
+		full := filepath.Join(s.dir, path)
+		require.NoError(s.t, os.MkdirAll(filepath.Dir(full), 0700))
+		require.NoError(s.t, ioutil.WriteFile(full, []byte(content), 0600))
+
+		_, err := wt.Add(path)
+		require.NoError(s.t, err)
+	}
+
+	hash, err := wt.Commit("update templates", &git.CommitOptions{
+		Author:  &object.Signature{Name: "gig", Email: "gig@example.com", When: when},
+		SignKey: s.signKey,
+	})
+	require.NoError(s.t, err)
+
+	return hash.String()
+}
@@ -0,0 +1,90 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shihanng/gig/internal/flock"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Concurrent(t *testing.T) {
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	for _, backend := range []string{repo.GoGitBackend, repo.GitBackend} {
+		backend := backend
+
+		t.Run(backend, func(t *testing.T) {
+			if _, err := exec.LookPath("git"); err != nil && backend == repo.GitBackend {
+				t.Skip("git is not installed")
+			}
+
+			dir, err := ioutil.TempDir("", "gig")
+			require.NoError(t, err)
+
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "cache")
+			opts := []repo.Option{repo.WithLockTimeout(time.Minute), repo.WithBackend(backend)}
+
+			var wg sync.WaitGroup
+
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+
+				go func(i int) {
+					defer wg.Done()
+
+					r, err := repo.New(path, source.dir, opts...)
+					if !assert.NoError(t, err) {
+						return
+					}
+
+					if i%2 == 0 {
+						_, _, err := repo.Update(r, opts...)
+						assert.NoError(t, err)
+					}
+
+					got, _, err := repo.Checkout(r, "", opts...)
+					assert.NoError(t, err)
+					assert.Equal(t, want, got)
+				}(i)
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestNew_LockTimeout(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache")
+
+	holder := flock.New(path + ".lock")
+	require.NoError(t, holder.Lock(0, nil))
+
+	defer holder.Unlock()
+
+	var waitedFor string
+
+	_, err = repo.New(path, source.dir,
+		repo.WithLockTimeout(200*time.Millisecond),
+		repo.WithLockWait(func(lockPath string) { waitedFor = lockPath }))
+	assert.Error(t, err)
+	assert.Equal(t, path+".lock", waitedFor)
+}
@@ -0,0 +1,347 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// gitBackend runs the git binary. The clone is partial, without the contents
+// of the files, which are fetched for a commit when it is checked out.
+// It is also shallow, with only the latest commit of the default branch,
+// unless WithShallow(false) is given, see Deepen.
+// Sources that do not support partial clones are cloned completely.
+type gitBackend struct{}
+
+func (gitBackend) clone(path, source string, o *options) error {
+	args := []string{"clone", "--bare", "--quiet", "--filter=blob:none"}
+	if o.shallow {
+		args = append(args, "--depth=1", "--single-branch")
+	}
+
+	_, err := runGit(o.ctx, "", o.gitEnv(source), nil, append(args, source, path)...)
+	if err != nil {
+		return redactError(err, source, o.password)
+	}
+
+	out, err := runGit(o.ctx, path, nil, nil, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	branch := strings.TrimSpace(string(out))
+
+	// A shallow clone only fetches its branch, new branches would bring in their history.
+	fetch := allBranches
+	if o.shallow {
+		fetch = "+refs/heads/" + branch + ":refs/remotes/" + git.DefaultRemoteName + "/" + branch
+	}
+
+	// Set up the remote branches and the tracking branch like go-git does,
+	// a bare clone of git has neither.
+	for _, args := range [][]string{
+		{"config", "remote." + git.DefaultRemoteName + ".fetch", fetch},
+		{"config", "branch." + branch + ".remote", git.DefaultRemoteName},
+		{"config", "branch." + branch + ".merge", "refs/heads/" + branch},
+		{"update-ref", "refs/remotes/" + git.DefaultRemoteName + "/" + branch, "refs/heads/" + branch},
+	} {
+		if _, err := runGit(o.ctx, path, nil, nil, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gitBackend) fetch(r *git.Repository, source string, o *options) error {
+	path, err := gitDir(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = runGit(o.ctx, path, o.gitEnv(source), nil, "fetch", "--quiet", git.DefaultRemoteName)
+	if err != nil {
+		return redactError(err, source, o.password)
+	}
+
+	reindex(r)
+
+	return nil
+}
+
+// allBranches is the refspec that fetches every branch of the remote.
+const allBranches = "+refs/heads/*:refs/remotes/" + git.DefaultRemoteName + "/*"
+
+// deepen fetches the whole history of every branch and the tags into the shallow clone r.
+func (gitBackend) deepen(r *git.Repository, source string, o *options) error {
+	path, err := gitDir(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = runGit(o.ctx, path, nil, nil, "config", "remote."+git.DefaultRemoteName+".fetch", allBranches)
+	if err != nil {
+		return err
+	}
+
+	_, err = runGit(o.ctx, path, o.gitEnv(source), nil, "fetch", "--quiet", "--unshallow", "--tags", git.DefaultRemoteName)
+	if err != nil {
+		return redactError(err, source, o.password)
+	}
+
+	reindex(r)
+
+	return nil
+}
+
+func (b gitBackend) checkout(r *git.Repository, hash plumbing.Hash, o *options) (fs.FS, error) {
+	if err := b.fetchMissing(r, hash, false, o); err != nil {
+		return nil, err
+	}
+
+	return goGitBackend{}.checkout(r, hash, o)
+}
+
+// fetchMissing fetches the objects of the commit hash that are missing in
+// the partial clone r, and those of its history when history is true.
+func (gitBackend) fetchMissing(r *git.Repository, hash plumbing.Hash, history bool, o *options) error {
+	path, err := gitDir(r)
+	if err != nil {
+		return err
+	}
+
+	args := []string{hash.String()}
+	if !history {
+		args = append(args, "--no-walk")
+	}
+
+	missing, err := missingObjects(o.ctx, path, args...)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	if !history && o.sparse != nil {
+		if missing, err = sparseObjects(o.ctx, path, hash, missing, o.sparse); err != nil || len(missing) == 0 {
+			return err
+		}
+	}
+
+	if o.offline {
+		return errors.Wrapf(ErrOffline, "repo: files of commit %s", hash)
+	}
+
+	source, err := remoteURL(r)
+	if err != nil {
+		return err
+	}
+
+	stdin := strings.NewReader(strings.Join(missing, "\n") + "\n")
+
+	// This is how git itself fetches the missing objects of partial clones,
+	// without negotiation, which fails for shallow clones.
+	_, err = runGit(o.ctx, path, o.gitEnv(source), stdin, "-c", "fetch.negotiationAlgorithm=noop", "fetch", "--quiet",
+		"--no-tags", "--no-write-fetch-head", "--recurse-submodules=no", "--filter=blob:none", "--stdin",
+		git.DefaultRemoteName)
+	if err != nil {
+		return errors.Wrapf(redactError(err, source, o.password), "repo: fetch files of commit %s", hash)
+	}
+
+	reindex(r)
+
+	return nil
+}
+
+// missingObjects returns the hashes of the objects that are missing in
+// the partial clone in path and reachable from the revisions of args.
+// They are listed without fetching them one by one.
+func missingObjects(ctx context.Context, path string, args ...string) ([]string, error) {
+	out, err := runGit(ctx, path, nil, nil, append([]string{"rev-list", "--objects", "--missing=print"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "?") {
+			missing = append(missing, line[1:])
+		}
+	}
+
+	return missing, errors.Wrap(scanner.Err(), "repo: read missing objects")
+}
+
+// sparseObjects returns the objects of missing that are not files of the
+// commit hash or are files whose name match returns true for.
+// The trees of the commit are never missing from a partial clone.
+func sparseObjects(ctx context.Context, path string, hash plumbing.Hash, missing []string,
+	match func(name string) bool) ([]string, error) {
+	out, err := runGit(ctx, path, nil, nil, "ls-tree", "-r", "-z", "--full-tree", hash.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// A file might have the same content, and thus object, as another one.
+	files := make(map[string]bool)
+
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// An entry is "<mode> <type> <object>\t<name>".
+		fields := strings.SplitN(entry, "\t", 2) //nolint:gomnd
+		if len(fields) != 2 {                    //nolint:gomnd
+			continue
+		}
+
+		info := strings.Fields(fields[0])
+		if len(info) != 3 || info[1] != "blob" { //nolint:gomnd
+			continue
+		}
+
+		files[info[2]] = files[info[2]] || match(fields[1])
+	}
+
+	var sparse []string
+
+	for _, object := range missing {
+		if read, ok := files[object]; !ok || read {
+			sparse = append(sparse, object)
+		}
+	}
+
+	return sparse, nil
+}
+
+func (gitBackend) prune(r *git.Repository, o *options) error {
+	path, err := gitDir(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = runGit(o.ctx, path, nil, nil, "gc", "--quiet", "--prune=now")
+
+	return err
+}
+
+// gitEnv returns the environment of the git commands that access source.
+// Explicit credentials, or those of the netrc file, are passed in a header
+// through the environment so that they never show up in the process list.
+// Otherwise git asks its credential helpers.
+func (o *options) gitEnv(source string) []string {
+	u, ok := httpURL(source)
+	if !ok {
+		return gitEnv(nil)
+	}
+
+	auth, ok := o.auth(u).(*http.BasicAuth)
+	if !ok {
+		return gitEnv(nil)
+	}
+
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+
+	return gitEnv([]string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=" + header})
+}
+
+// gitEnv returns the environment of git commands that might access the network,
+// where git never prompts in the terminal.
+func gitEnv(extra []string) []string {
+	return append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never"), extra...)
+}
+
+// runGit runs git with args in dir and returns its output. The error
+// contains what git printed to the standard error.
+func runGit(ctx context.Context, dir string, env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...) //nolint:gosec
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdin = stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	name := args[0]
+	if name == "-c" && len(args) > 2 {
+		name = args[2]
+	}
+
+	if err := run(ctx, cmd); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrapf(err, "repo: git %s: %s", name, msg)
+		}
+
+		return nil, errors.Wrapf(err, "repo: git %s", name)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// stopDelay is how long a command has to exit after it was asked to stop.
+const stopDelay = 5 * time.Second
+
+// run runs cmd until it exits or ctx is done. Unlike exec.CommandContext,
+// which kills the command right away, git is asked to stop first so that
+// it removes its lock files from the cache.
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		_ = interrupt(cmd)
+
+		select {
+		case <-done:
+		case <-time.After(stopDelay):
+			_ = kill(cmd)
+		}
+	}()
+
+	err := cmd.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// gitDir returns the directory of r for running git in it.
+func gitDir(r *git.Repository) (string, error) {
+	fs, ok := storageFilesystem(r)
+	if !ok {
+		return "", errors.New("repo: the git backend needs a repository on the file system")
+	}
+
+	return fs.Root(), nil
+}
+
+// reindex makes r see the objects that git added to the cache.
+func reindex(r *git.Repository) {
+	if s, ok := r.Storer.(*filesystem.Storage); ok {
+		s.Reindex()
+	}
+}
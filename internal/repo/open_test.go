@@ -0,0 +1,30 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	_, err = repo.Open(dir)
+	assert.ErrorIs(t, err, repo.ErrNotCached)
+
+	_, err = repo.New(dir, source.dir)
+	require.NoError(t, err)
+
+	_, err = repo.Open(dir)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/shihanng/gig/internal/flock"
+)
+
+// DefaultLockTimeout is how long to wait for other processes
+// using the same cache by default.
+const DefaultLockTimeout = 5 * time.Minute
+
+// Option configures how the cached repository is accessed.
+type Option func(*options)
+
+type options struct {
+	ctx context.Context
+
+	lockTimeout time.Duration
+	onLockWait  func(lockPath string)
+
+	username string
+	password string
+
+	trustedKeys string
+	onVerified  func(signer string)
+
+	backendName string
+	shallow     bool
+	sparse      func(name string) bool
+	offline     bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{ctx: context.Background(), lockTimeout: DefaultLockTimeout, backendName: GoGitBackend, shallow: true}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithContext sets the context that cancels cloning, fetching, and waiting
+// for the lock of the cache. A canceled clone leaves no cache behind.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithLockTimeout sets how long to wait for other processes that
+// are cloning, fetching, or reading the same cache.
+func WithLockTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.lockTimeout = d
+	}
+}
+
+// WithLockWait sets fn to be called when the cache is locked by
+// another process and we have to wait for it.
+func WithLockWait(fn func(lockPath string)) Option {
+	return func(o *options) {
+		o.onLockWait = fn
+	}
+}
+
+// WithCredentials sets the username and password, or token, used to
+// clone and fetch sources over HTTP. Without them the credentials are
+// taken from the netrc file or the git credential helper.
+func WithCredentials(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithTrustedKeys sets the armored OpenPGP keyring of the keys that must
+// have signed the commits used by Checkout and fetched by Update.
+// Without it any commit is used.
+func WithTrustedKeys(armoredKeyRing string) Option {
+	return func(o *options) {
+		o.trustedKeys = armoredKeyRing
+	}
+}
+
+// WithVerified sets fn to be called with the signer of the commit that
+// Checkout verified, see WithTrustedKeys.
+func WithVerified(fn func(signer string)) Option {
+	return func(o *options) {
+		o.onVerified = fn
+	}
+}
+
+// WithBackend sets the backend, GoGitBackend or GitBackend, that clones
+// and fetches the cache and checks out commits. A partial clone of
+// the git backend is always checked out with the git backend.
+func WithBackend(name string) Option {
+	return func(o *options) {
+		o.backendName = name
+	}
+}
+
+// WithShallow sets whether the git backend clones only the latest commit
+// of the default branch. The history is fetched later when needed, see Deepen.
+func WithShallow(shallow bool) Option {
+	return func(o *options) {
+		o.shallow = shallow
+	}
+}
+
+// WithSparse sets which files of a commit Checkout reads, those for which
+// match returns true. The git backend fetches only the missing files among
+// them into a partial clone, the others cannot be read from the returned fs.FS.
+func WithSparse(match func(name string) bool) Option {
+	return func(o *options) {
+		o.sparse = match
+	}
+}
+
+// WithOffline sets whether the network must never be accessed. The files
+// that a partial clone left out cannot be fetched then, see ErrOffline.
+func WithOffline(offline bool) Option {
+	return func(o *options) {
+		o.offline = offline
+	}
+}
+
+// lock locks the cache in path across processes. The lock file is
+// placed next to the cache directory because the directory might not exist yet.
+func (o *options) lock(path string, exclusive bool) (*flock.Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil { //nolint:gomnd
+		return nil, errors.Wrap(err, "repo: create cache parent directory")
+	}
+
+	l := flock.New(filepath.Clean(path) + ".lock")
+
+	var onWait func()
+	if o.onLockWait != nil {
+		onWait = func() { o.onLockWait(l.Path) }
+	}
+
+	lock := l.RLockContext
+	if exclusive {
+		lock = l.LockContext
+	}
+
+	return l, lock(o.ctx, o.lockTimeout, onWait)
+}
+
+// lockRepo is like lock for the cache of r. It returns a nil lock,
+// which is safe to unlock, when r is not stored on the file system.
+func (o *options) lockRepo(r *git.Repository, exclusive bool) (*flock.Lock, error) {
+	fs, ok := storageFilesystem(r)
+	if !ok {
+		return nil, nil
+	}
+
+	path := fs.Root()
+	if filepath.Base(path) == git.GitDirName {
+		path = filepath.Dir(path)
+	}
+
+	return o.lock(path, exclusive)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package repo
+
+import (
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// interrupt stops cmd right away, asking it to stop is not supported on Windows.
+func interrupt(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
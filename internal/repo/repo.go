@@ -1,59 +1,404 @@
 package repo
 
 import (
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
 )
 
 const SourceRepo = `https://github.com/toptal/gitignore.git`
 
-func New(path, repoSource string) (*git.Repository, error) {
-	repo, err := git.PlainClone(path, false, &git.CloneOptions{
-		URL:      repoSource,
-		Progress: ioutil.Discard,
-	})
+// ErrNotCached is returned by Open when path does not contain a repository.
+var ErrNotCached = errors.New("repo: templates are not cached")
 
-	if err == nil {
-		return repo, nil
+// ErrOffline is returned when files that a partial clone left out are needed
+// but cannot be fetched because of WithOffline.
+var ErrOffline = errors.New("not cached, run without --offline to fetch them")
+
+// New opens the repository cached in path or clones repoSource into path
+// when there is none yet.
+func New(path, repoSource string, opts ...Option) (*git.Repository, error) {
+	repo, err := Open(path)
+	if !errors.Is(err, ErrNotCached) {
+		return repo, err
+	}
+
+	o := newOptions(opts)
+
+	l, err := o.lock(path, true)
+	if err != nil {
+		return nil, err
 	}
 
-	if !errors.Is(err, git.ErrRepositoryAlreadyExists) {
+	defer l.Unlock() //nolint:errcheck
+
+	// Another process might have cloned it while we were waiting for the lock.
+	repo, err = Open(path)
+	if !errors.Is(err, ErrNotCached) {
+		return repo, err
+	}
+
+	return clone(path, repoSource, o)
+}
+
+// clone clones repoSource into a temporary directory next to path
+// and moves it into place once it is complete so that others
+// never see a partially cloned repository.
+func clone(path, repoSource string, o *options) (*git.Repository, error) {
+	parent, base := filepath.Split(filepath.Clean(path))
+
+	tmp, err := ioutil.TempDir(parent, "."+base+".clone-")
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: create temporary directory")
+	}
+
+	defer os.RemoveAll(tmp)
+
+	b, err := o.backend()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.clone(tmp, repoSource, o); err != nil {
 		return nil, errors.Wrap(err, "repo: failed to clone")
 	}
 
-	repo, err = git.PlainOpen(path)
+	repo, err := Open(tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordFetch(repo); err != nil {
+		return nil, err
+	}
+
+	// Rename does not replace a directory on every platform,
+	// an empty one is left behind by callers that prepared the path.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "repo: remove cache directory")
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, errors.Wrap(err, "repo: move clone into cache directory")
+	}
+
+	return Open(path)
+}
+
+// Open opens the repository cached in path without accessing the network.
+func Open(path string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(path)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, ErrNotCached
+	}
 
 	return repo, errors.Wrap(err, "repo: failed open repo")
 }
 
-type repoer interface {
-	Worktree() (*git.Worktree, error)
-	Head() (*plumbing.Reference, error)
+// Checkout resolves rev, see Resolve for the supported formats, and returns
+// its commit hash together with a read-only fs.FS of the files of that commit.
+// The files are read from the object store so the repository is never modified,
+// apart from the files the git backend fetches for a partial clone,
+// and different commits can be used concurrently.
+// An empty rev refers to the branch tracking the remote.
+// The commit must be signed by a trusted key when WithTrustedKeys is given,
+// its signer is passed to the function given with WithVerified.
+func Checkout(r *git.Repository, rev string, opts ...Option) (string, fs.FS, error) {
+	o := newOptions(opts)
+
+	b, err := o.backendFor(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return o.checkout(r, rev, b)
+}
+
+func (o *options) checkout(r *git.Repository, rev string, b backend) (string, fs.FS, error) {
+	l, err := o.lockRepo(r, false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	if rev == "" {
+		if branch, err := trackingBranch(r); err == nil {
+			rev = string(plumbing.NewBranchReferenceName(branch.Name))
+		}
+	}
+
+	hash, err := Resolve(r, rev)
+	if err != nil {
+		return "", nil, err
+	}
+
+	signer, err := o.verify(r, hash.String())
+	if err != nil {
+		return "", nil, err
+	}
+
+	if signer != "" && o.onVerified != nil {
+		o.onVerified(signer)
+	}
+
+	fsys, err := b.checkout(r, hash, o)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return hash.String(), fsys, nil
+}
+
+// Update fetches the latest commits from the remote of r and moves
+// the branch tracking the remote to the fetched commit. It returns
+// the commit hashes of the branch before and after the update.
+// The branch is not moved when the fetched commit is not signed by
+// a trusted key, see WithTrustedKeys.
+func Update(r *git.Repository, opts ...Option) (string, string, error) {
+	o := newOptions(opts)
+
+	l, err := o.lockRepo(r, true)
+	if err != nil {
+		return "", "", err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	branch, err := trackingBranch(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	localName := plumbing.NewBranchReferenceName(branch.Name)
+
+	old, err := r.Reference(localName, true)
+	if err != nil {
+		return "", "", errors.Wrap(err, "repo: get branch reference")
+	}
+
+	source, err := remoteURL(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	b, err := o.backendFor(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = b.fetch(r, source, o)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", "", errors.Wrap(err, "repo: fetch")
+	}
+
+	if err := recordFetch(r); err != nil {
+		return "", "", err
+	}
+
+	remoteRef, err := r.Reference(plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short()), true)
+	if err != nil {
+		return "", "", errors.Wrap(err, "repo: get remote reference")
+	}
+
+	if _, err := o.verify(r, remoteRef.Hash().String()); err != nil {
+		return "", "", err
+	}
+
+	localRef := plumbing.NewHashReference(localName, remoteRef.Hash())
+	if err := r.Storer.SetReference(localRef); err != nil {
+		return "", "", errors.Wrap(err, "repo: set branch reference")
+	}
+
+	return old.Hash().String(), remoteRef.Hash().String(), nil
+}
+
+// Deepen fetches the whole history and the tags into r when it is a shallow
+// clone, see WithShallow, so that older commits can be checked out.
+// It does nothing otherwise.
+func Deepen(r *git.Repository, opts ...Option) error {
+	if !IsShallow(r) {
+		return nil
+	}
+
+	o := newOptions(opts)
+
+	l, err := o.lockRepo(r, true)
+	if err != nil {
+		return err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	source, err := remoteURL(r)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(gitBackend{}.deepen(r, source, o), "repo: deepen")
+}
+
+// remoteURL returns the URL of the default remote of r.
+func remoteURL(r *git.Repository) (string, error) {
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return "", errors.Wrap(err, "repo: get remote")
+	}
+
+	if len(remote.Config().URLs) == 0 {
+		return "", errors.New("repo: remote has no URL")
+	}
+
+	return remote.Config().URLs[0], nil
 }
 
-func Checkout(r repoer, commitHash string) (string, error) {
-	wt, err := r.Worktree()
+// trackingBranch returns the local branch that was set up to track
+// the default remote when the repository was cloned.
+func trackingBranch(r *git.Repository) (*config.Branch, error) {
+	cfg, err := r.Config()
 	if err != nil {
-		return "", errors.Wrap(err, "repo: getting worktree")
+		return nil, errors.Wrap(err, "repo: get config")
 	}
 
-	opts := git.CheckoutOptions{Force: true}
+	for _, b := range cfg.Branches {
+		if b.Remote == git.DefaultRemoteName {
+			return b, nil
+		}
+	}
 
-	if commitHash != "" {
-		opts.Hash = plumbing.NewHash(commitHash)
+	return nil, errors.New("repo: no branch tracking the remote")
+}
+
+// lastFetchFile is stored in the git directory of the repository and
+// contains the time of the last successful clone or fetch.
+const lastFetchFile = "gig_last_fetch"
+
+// LastFetch returns the time r was last cloned or fetched by gig.
+// It returns the zero time when it is unknown.
+func LastFetch(r *git.Repository) (time.Time, error) {
+	fs, ok := storageFilesystem(r)
+	if !ok {
+		return time.Time{}, nil
 	}
 
-	if err := wt.Checkout(&opts); err != nil {
-		return "", errors.Wrap(err, "repo: checkout")
+	f, err := fs.Open(lastFetchFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, errors.Wrap(err, "repo: open last fetch time")
 	}
 
-	ref, err := r.Head()
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
 	if err != nil {
-		return "", errors.Wrap(err, "repo: get head")
+		return time.Time{}, errors.Wrap(err, "repo: read last fetch time")
 	}
 
-	return ref.Hash().String(), nil
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+
+	return t, errors.Wrap(err, "repo: parse last fetch time")
+}
+
+func recordFetch(r *git.Repository) error {
+	fs, ok := storageFilesystem(r)
+	if !ok {
+		return nil
+	}
+
+	f, err := fs.Create(lastFetchFile)
+	if err != nil {
+		return errors.Wrap(err, "repo: create last fetch time")
+	}
+
+	defer f.Close()
+
+	_, err = io.WriteString(f, time.Now().UTC().Format(time.RFC3339)+"\n")
+
+	return errors.Wrap(err, "repo: write last fetch time")
+}
+
+func storageFilesystem(r *git.Repository) (billy.Filesystem, bool) {
+	s, ok := r.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, false
+	}
+
+	return s.Filesystem(), true
+}
+
+// Change describes a file that differs between two commits.
+// Action is one of "added", "modified", or "deleted".
+type Change struct {
+	Action string
+	Path   string
+}
+
+// Diff returns the files that changed between the commits from and to.
+func Diff(r *git.Repository, from, to string) ([]Change, error) {
+	fromTree, err := commitTree(r, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTree, err := commitTree(r, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: diff tree")
+	}
+
+	result := make([]Change, 0, len(changes))
+
+	for _, ch := range changes {
+		action, err := ch.Action()
+		if err != nil {
+			return nil, errors.Wrap(err, "repo: get change action")
+		}
+
+		path := ch.To.Name
+		if path == "" {
+			path = ch.From.Name
+		}
+
+		result = append(result, Change{Action: actions[action], Path: path})
+	}
+
+	return result, nil
+}
+
+//nolint:gochecknoglobals
+var actions = map[merkletrie.Action]string{
+	merkletrie.Insert: "added",
+	merkletrie.Modify: "modified",
+	merkletrie.Delete: "deleted",
+}
+
+func commitTree(r *git.Repository, hash string) (*object.Tree, error) {
+	commit, err := r.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, errors.Wrapf(err, "repo: get commit %s", hash)
+	}
+
+	tree, err := commit.Tree()
+
+	return tree, errors.Wrapf(err, "repo: get tree of %s", hash)
 }
@@ -118,7 +118,7 @@ func (s *RepoSuite) TestCheckout() {
 
 	for _, tt := range tests {
 		s.T().Run(tt.name, func(t *testing.T) {
-			got, err := repo.Checkout(repository, tt.args.commitHash)
+			got, _, err := repo.Checkout(repository, tt.args.commitHash)
 			tt.assertion(t, err)
 			assert.Equal(t, tt.want, got)
 		})
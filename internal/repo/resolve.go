@@ -0,0 +1,273 @@
+package repo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// ErrUnknownRevision is returned when a revision does not match any commit
+// of the repository, e.g. because it is older than a shallow clone.
+var ErrUnknownRevision = errors.New("unknown revision")
+
+// minAbbrevLength is the minimum length of an abbreviated commit hash.
+const minAbbrevLength = 4
+
+//nolint:gochecknoglobals
+var (
+	dateRevRegexp = regexp.MustCompile(`^(.*)@\{(.+)\}$`)
+	hexRegexp     = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+	dateLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+
+	// refRules are the places where a short reference name is looked up.
+	refRules = []string{
+		"%s",
+		"refs/tags/%s",
+		"refs/heads/%s",
+		"refs/remotes/%s",
+		"refs/remotes/" + git.DefaultRemoteName + "/%s",
+	}
+)
+
+// Resolve returns the commit hash that rev refers to in r. rev can be
+// a branch, a tag, a full or abbreviated commit hash, or any of them
+// followed by @{date} for the last commit made at or before that date,
+// e.g. master@{2020-01-31}. An empty rev resolves to HEAD.
+func Resolve(r *git.Repository, rev string) (plumbing.Hash, error) {
+	if m := dateRevRegexp.FindStringSubmatch(rev); m != nil {
+		return resolveDate(r, m[1], m[2])
+	}
+
+	if rev == "" {
+		rev = string(plumbing.HEAD)
+	}
+
+	candidates, err := resolveRefs(r, rev)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if len(candidates) == 0 && hexRegexp.MatchString(rev) {
+		candidates, err = resolveHash(r, rev)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return plumbing.ZeroHash, errors.Wrapf(ErrUnknownRevision, "repo: %q", rev)
+	case 1:
+		for hash := range candidates {
+			return hash, nil
+		}
+	}
+
+	return plumbing.ZeroHash, errors.Errorf("repo: revision %q is ambiguous, it could be any of: %s",
+		rev, strings.Join(sortedValues(candidates), ", "))
+}
+
+// resolveRefs returns the commit of the first reference that rev matches
+// keyed by the commit hash. Like git, a branch and a tag with the same name
+// pointing to different commits make rev ambiguous and both are returned.
+func resolveRefs(r *git.Repository, rev string) (map[plumbing.Hash]string, error) {
+	candidates := make(map[plumbing.Hash]string)
+
+	for _, rule := range refRules {
+		name := plumbing.ReferenceName(fmt.Sprintf(rule, rev))
+
+		ref, err := r.Reference(name, true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "repo: get reference %s", name)
+		}
+
+		hash, err := peel(r, ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+
+		if _, found := candidates[hash]; !found {
+			candidates[hash] = string(name)
+		}
+
+		if !name.IsTag() {
+			break
+		}
+	}
+
+	return candidates, nil
+}
+
+// peel returns the commit that hash points to when it is an annotated tag.
+func peel(r *git.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	tag, err := r.TagObject(hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return hash, nil
+	}
+
+	if err != nil {
+		return plumbing.ZeroHash, errors.Wrapf(err, "repo: get tag %s", hash)
+	}
+
+	commit, err := tag.Commit()
+	if err != nil {
+		return plumbing.ZeroHash, errors.Wrapf(err, "repo: get commit of tag %s", tag.Name)
+	}
+
+	return commit.Hash, nil
+}
+
+// resolveHash returns the commits whose hash starts with prefix.
+func resolveHash(r *git.Repository, prefix string) (map[plumbing.Hash]string, error) {
+	prefix = strings.ToLower(prefix)
+
+	if len(prefix) == len(plumbing.ZeroHash.String()) {
+		hash := plumbing.NewHash(prefix)
+		if _, err := r.CommitObject(hash); err != nil {
+			return nil, nil //nolint:nilerr
+		}
+
+		return map[plumbing.Hash]string{hash: prefix}, nil
+	}
+
+	if len(prefix) < minAbbrevLength {
+		return nil, errors.Errorf("repo: abbreviated commit hash %q is shorter than %d characters",
+			prefix, minAbbrevLength)
+	}
+
+	iter, err := r.CommitObjects()
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: list commits")
+	}
+
+	candidates := make(map[plumbing.Hash]string)
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if strings.HasPrefix(c.Hash.String(), prefix) {
+			candidates[c.Hash] = c.Hash.String()
+		}
+
+		return nil
+	})
+
+	return candidates, errors.Wrap(err, "repo: search commits")
+}
+
+// resolveDate returns the last commit reachable from rev that was made at
+// or before date.
+func resolveDate(r *git.Repository, rev, date string) (plumbing.Hash, error) {
+	until, err := parseDate(date)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	from, err := Resolve(r, rev)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	commit, err := r.CommitObject(from)
+	if err != nil {
+		return plumbing.ZeroHash, errors.Wrapf(err, "repo: get commit %s", from)
+	}
+
+	// The parents of the commits at the boundary of a shallow clone are not
+	// cached, the walk stops there and older commits are unknown.
+	boundary, err := shallowParents(r)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	iter := object.NewCommitIterCTime(commit, nil, boundary)
+
+	found := plumbing.ZeroHash
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Committer.When.After(until) {
+			return nil
+		}
+
+		found = c.Hash
+
+		return storer.ErrStop
+	})
+	if err != nil {
+		return plumbing.ZeroHash, errors.Wrap(err, "repo: walk log")
+	}
+
+	if found.IsZero() {
+		return plumbing.ZeroHash, errors.Wrapf(ErrUnknownRevision, "repo: no commit at or before %s", date)
+	}
+
+	return found, nil
+}
+
+// shallowParents returns the parents of the commits at the boundary of
+// the shallow clone r, none when r is not shallow.
+func shallowParents(r *git.Repository) ([]plumbing.Hash, error) {
+	shallow, err := r.Storer.Shallow()
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: get shallow commits")
+	}
+
+	var parents []plumbing.Hash
+
+	for _, hash := range shallow {
+		commit, err := r.CommitObject(hash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "repo: get commit %s", hash)
+		}
+
+		parents = append(parents, commit.ParentHashes...)
+	}
+
+	return parents, nil
+}
+
+func parseDate(date string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, date, time.Local)
+		if err != nil {
+			continue
+		}
+
+		// A date without time means the end of that day.
+		if layout == "2006-01-02" {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+
+		return t, nil
+	}
+
+	return time.Time{}, errors.Errorf("repo: invalid date %q, use YYYY-MM-DD or RFC 3339", date)
+}
+
+func sortedValues(m map[plumbing.Hash]string) []string {
+	values := make([]string, 0, len(m))
+
+	for _, v := range m {
+		values = append(values, v)
+	}
+
+	sort.Strings(values)
+
+	return values
+}
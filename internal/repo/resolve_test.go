@@ -0,0 +1,119 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	source := newSourceRepo(t)
+
+	first := source.commitAt(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+		map[string]string{"templates/Go.gitignore": "*.exe\n"})
+	second := source.commitAt(time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC),
+		map[string]string{"templates/Go.gitignore": "*.exe\n*.test\n"})
+	third := source.commitAt(time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC),
+		map[string]string{"templates/Elm.gitignore": "elm-stuff\n"})
+
+	_, err := source.repo.CreateTag("v1", plumbing.NewHash(first), nil)
+	require.NoError(t, err)
+
+	_, err = source.repo.CreateTag("v2", plumbing.NewHash(second), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "gig", Email: "gig@example.com", When: time.Now()},
+		Message: "v2",
+	})
+	require.NoError(t, err)
+
+	_, err = source.repo.CreateTag("dup", plumbing.NewHash(second), nil)
+	require.NoError(t, err)
+	require.NoError(t, source.repo.Storer.SetReference(
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("dup"), plumbing.NewHash(first))))
+
+	unknownRevision := func(t assert.TestingT, err error, msgAndArgs ...interface{}) bool {
+		return assert.ErrorIs(t, err, repo.ErrUnknownRevision, msgAndArgs...)
+	}
+
+	tests := []struct {
+		name      string
+		rev       string
+		want      string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{name: "empty", rev: "", want: third, assertion: assert.NoError},
+		{name: "branch", rev: "master", want: third, assertion: assert.NoError},
+		{name: "lightweight tag", rev: "v1", want: first, assertion: assert.NoError},
+		{name: "annotated tag", rev: "v2", want: second, assertion: assert.NoError},
+		{name: "full hash", rev: second, want: second, assertion: assert.NoError},
+		{name: "abbreviated hash", rev: first[:7], want: first, assertion: assert.NoError},
+		{name: "date", rev: "@{2020-02-15}", want: second, assertion: assert.NoError},
+		{name: "branch at date", rev: "master@{2020-01-02}", want: first, assertion: assert.NoError},
+		{name: "date and time", rev: "@{2020-03-01T11:00:00Z}", want: second, assertion: assert.NoError},
+		{name: "date before history", rev: "@{2019-12-31}", want: "", assertion: unknownRevision},
+		{name: "invalid date", rev: "@{last week}", want: "", assertion: assert.Error},
+		{name: "ambiguous", rev: "dup", want: "", assertion: assert.Error},
+		{name: "unknown", rev: "unknown", want: "", assertion: unknownRevision},
+		{name: "unknown hash", rev: "58e32169bcb1b615cc8f4820e0299d07c6a679d2", want: "", assertion: unknownRevision},
+		{name: "too short", rev: "abc", want: "", assertion: assert.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Resolve(source.repo, tt.rev)
+			tt.assertion(t, err)
+
+			if tt.want == "" {
+				return
+			}
+
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestResolve_Shallow(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	source := newSourceRepo(t)
+
+	first := source.commitAt(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+		map[string]string{"templates/Go.gitignore": "*.exe\n"})
+	second := source.commitAt(time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC),
+		map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := repo.WithBackend(repo.GitBackend)
+
+	r, err := repo.New(filepath.Join(dir, "cache"), "file://"+filepath.ToSlash(source.dir), backend)
+	require.NoError(t, err)
+	require.True(t, repo.IsShallow(r))
+
+	got, err := repo.Resolve(r, "@{2020-02-15}")
+	assert.NoError(t, err)
+	assert.Equal(t, second, got.String(), "the commit at the boundary is cached")
+
+	_, err = repo.Resolve(r, "@{2020-01-15}")
+	assert.ErrorIs(t, err, repo.ErrUnknownRevision, "older commits are not cached")
+
+	require.NoError(t, repo.Deepen(r, backend))
+
+	got, err = repo.Resolve(r, "@{2020-01-15}")
+	assert.NoError(t, err)
+	assert.Equal(t, first, got.String())
+}
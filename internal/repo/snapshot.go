@@ -0,0 +1,264 @@
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+)
+
+// SnapshotFile is the file of a snapshot that describes it.
+const SnapshotFile = ".gig-snapshot.json"
+
+// Snapshot describes the files of a commit of a templates repository
+// that are cached without the repository itself, e.g. because it cannot
+// be reached. A snapshot cannot be updated, only replaced.
+type Snapshot struct {
+	Commit  string    `json:"commit"`
+	Source  string    `json:"source"`
+	Created time.Time `json:"created"`
+}
+
+// OpenSnapshot returns the snapshot cached in path and its files.
+// It returns ErrNotCached when there is no snapshot in path.
+func OpenSnapshot(path string) (*Snapshot, fs.FS, error) {
+	content, err := ioutil.ReadFile(filepath.Join(path, SnapshotFile))
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotCached
+	}
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "repo: read snapshot")
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, nil, errors.Wrap(err, "repo: parse snapshot")
+	}
+
+	return &s, os.DirFS(path), nil
+}
+
+// ExportSnapshot writes the files of the commit rev of r, see Checkout,
+// to w as a gzipped tarball that can be imported with ImportSnapshot.
+// It returns the commit hash of rev.
+func ExportSnapshot(w io.Writer, r *git.Repository, rev, source string, opts ...Option) (string, error) {
+	hash, fsys, err := Checkout(r, rev, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, WriteSnapshot(w, &Snapshot{
+		Commit:  hash,
+		Source:  RedactSource(source),
+		Created: time.Now().UTC().Truncate(time.Second),
+	}, fsys)
+}
+
+// WriteSnapshot writes s together with the files of fsys in dirs,
+// or all files when no dirs are given, to w as a gzipped tarball.
+func WriteSnapshot(w io.Writer, s *Snapshot, fsys fs.FS, dirs ...string) error {
+	snapshot, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "repo: marshal snapshot")
+	}
+
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := writeTarFile(tw, SnapshotFile, append(snapshot, '\n')); err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+
+			return writeTarFile(tw, name, content)
+		})
+		if err != nil {
+			return errors.Wrap(err, "repo: write snapshot")
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "repo: write snapshot")
+	}
+
+	return errors.Wrap(gw.Close(), "repo: write snapshot")
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644, //nolint:gomnd
+		Size:     int64(len(content)),
+	}); err != nil {
+		return errors.Wrap(err, "repo: write snapshot header")
+	}
+
+	_, err := tw.Write(content)
+
+	return errors.Wrap(err, "repo: write snapshot file")
+}
+
+// ImportSnapshot replaces whatever is cached in path with the snapshot
+// in the gzipped tarball r, which was created by ExportSnapshot.
+func ImportSnapshot(path string, r io.Reader, opts ...Option) (*Snapshot, error) {
+	l, err := newOptions(opts).lock(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	parent, base := filepath.Split(filepath.Clean(path))
+
+	tmp, err := ioutil.TempDir(parent, "."+base+".import-")
+	if err != nil {
+		return nil, errors.Wrap(err, "repo: create temporary directory")
+	}
+
+	defer os.RemoveAll(tmp)
+
+	if err := extractTarball(tmp, r); err != nil {
+		return nil, err
+	}
+
+	s, _, err := OpenSnapshot(tmp)
+	if errors.Is(err, ErrNotCached) {
+		return nil, errors.Errorf("repo: %s not found in snapshot", SnapshotFile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, replaceCache(path, tmp)
+}
+
+// ReadSnapshot reads the snapshot in the gzipped tarball r, which was
+// created by ExportSnapshot or WriteSnapshot, into memory.
+func ReadSnapshot(r io.Reader) (*Snapshot, fs.FS, error) {
+	fsys := fstest.MapFS{}
+
+	err := readTarball(r, func(name string, h *tar.Header, r io.Reader) error {
+		if h.Typeflag == tar.TypeDir {
+			return nil
+		}
+
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return errors.Wrap(err, "repo: read snapshot file")
+		}
+
+		fsys[name] = &fstest.MapFile{Data: content, Mode: 0644, ModTime: h.ModTime} //nolint:gomnd
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, ok := fsys[SnapshotFile]
+	if !ok {
+		return nil, nil, errors.Errorf("repo: %s not found in snapshot", SnapshotFile)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(f.Data, &s); err != nil {
+		return nil, nil, errors.Wrap(err, "repo: parse snapshot")
+	}
+
+	return &s, fsys, nil
+}
+
+func extractTarball(dir string, r io.Reader) error {
+	return readTarball(r, func(name string, h *tar.Header, r io.Reader) error {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		if h.Typeflag == tar.TypeDir {
+			return errors.Wrap(os.MkdirAll(target, 0755), "repo: create snapshot directory") //nolint:gomnd
+		}
+
+		return extractFile(target, r)
+	})
+}
+
+// readTarball calls fn for every directory and regular file in the gzipped
+// tarball r with its cleaned name. Other files and names outside of the
+// tarball are rejected.
+func readTarball(r io.Reader, fn func(name string, h *tar.Header, r io.Reader) error) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "repo: read snapshot")
+	}
+
+	tr := tar.NewReader(gr)
+
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "repo: read snapshot")
+		}
+
+		name := path.Clean(h.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return errors.Errorf("repo: invalid file %s in snapshot", h.Name)
+		}
+
+		if h.Typeflag != tar.TypeDir && h.Typeflag != tar.TypeReg {
+			return errors.Errorf("repo: unsupported file %s in snapshot", h.Name)
+		}
+
+		if err := fn(name, h, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil { //nolint:gomnd
+		return errors.Wrap(err, "repo: create snapshot directory")
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) //nolint:gomnd
+	if err != nil {
+		return errors.Wrap(err, "repo: create snapshot file")
+	}
+
+	if _, err := io.Copy(f, r); err != nil { //nolint:gosec
+		f.Close()
+
+		return errors.Wrap(err, "repo: write snapshot file")
+	}
+
+	return errors.Wrap(f.Close(), "repo: write snapshot file")
+}
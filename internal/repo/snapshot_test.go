@@ -0,0 +1,134 @@
+package repo_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{
+		"templates/Go.gitignore": "*.exe\n",
+		"templates/order":        "go\n",
+	})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	r, err := repo.New(filepath.Join(dir, "connected"), source.dir)
+	require.NoError(t, err)
+
+	var snapshot bytes.Buffer
+
+	got, err := repo.ExportSnapshot(&snapshot, r, "", source.dir)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// A snapshot replaces the repository cached in path.
+	path := filepath.Join(dir, "airgapped")
+
+	_, err = repo.New(path, source.dir)
+	require.NoError(t, err)
+
+	s, err := repo.ImportSnapshot(path, &snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, want, s.Commit)
+	assert.Equal(t, source.dir, s.Source)
+
+	_, err = repo.Open(path)
+	assert.ErrorIs(t, err, repo.ErrNotCached)
+
+	s, fsys, err := repo.OpenSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, s.Commit)
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	problems, err := repo.Check(path, ".")
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	info, err := repo.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.Snapshot)
+	assert.Equal(t, want, info.Commit)
+}
+
+func TestImportSnapshot_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{name: "no snapshot file", files: map[string]string{"templates/Go.gitignore": "*.exe\n"}},
+		{name: "outside of the cache", files: map[string]string{"../evil": "evil\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gig")
+			require.NoError(t, err)
+
+			defer os.RemoveAll(dir)
+
+			var buf bytes.Buffer
+
+			gw := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gw)
+
+			for name, content := range tt.files {
+				require.NoError(t, tw.WriteHeader(&tar.Header{
+					Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(content)),
+				}))
+				_, err := tw.Write([]byte(content))
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, tw.Close())
+			require.NoError(t, gw.Close())
+
+			_, err = repo.ImportSnapshot(filepath.Join(dir, "cache"), &buf)
+			assert.Error(t, err)
+			assert.NoFileExists(t, filepath.Join(dir, "evil"))
+			assert.NoDirExists(t, filepath.Join(dir, "cache"))
+		})
+	}
+}
+
+func TestReadSnapshot(t *testing.T) {
+	files := fstest.MapFS{
+		"README.md":              {Data: []byte("# templates\n")},
+		"templates/Go.gitignore": {Data: []byte("*.exe\n")},
+		"templates/order":        {Data: []byte("go\n")},
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, repo.WriteSnapshot(&buf, &repo.Snapshot{Commit: "abc", Source: repo.SourceRepo}, files, "templates"))
+
+	s, fsys, err := repo.ReadSnapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", s.Commit)
+	assert.Equal(t, repo.SourceRepo, s.Source)
+
+	content, err := fs.ReadFile(fsys, "templates/Go.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.exe\n", string(content))
+
+	_, err = fs.Stat(fsys, "README.md")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
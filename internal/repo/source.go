@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+)
+
+// NormalizeSource returns source in the form used to clone it.
+// Local paths are made absolute so that the cache does not depend
+// on the working directory.
+func NormalizeSource(source string) (string, error) {
+	if strings.Contains(source, "://") || isSCPLike(source) {
+		return source, nil
+	}
+
+	abs, err := filepath.Abs(source)
+
+	return abs, errors.Wrap(err, "repo: get absolute path of source")
+}
+
+// isSCPLike reports whether source is of the form [user@]host:path.
+func isSCPLike(source string) bool {
+	i := strings.Index(source, ":")
+	if i <= 1 { // Also excludes Windows drive letters like C:\.
+		return false
+	}
+
+	return !strings.ContainsAny(source[:i], `/\`)
+}
+
+// DisplaySource returns a short form of source for messages,
+// e.g. github.com/toptal/gitignore for SourceRepo.
+// Credentials in source are redacted.
+func DisplaySource(source string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(RedactSource(source), "https://"), ".git")
+}
+
+// CachePath returns the directory in root where source is cached.
+// Every source has its own directory so that objects of different
+// sources are never mixed. Credentials in source are ignored.
+func CachePath(root, source string) string {
+	if u, ok := httpURL(source); ok {
+		u.User = nil
+		source = u.String()
+	}
+
+	sum := sha256.Sum256([]byte(source))
+
+	return filepath.Join(root, slug(source)+"-"+hex.EncodeToString(sum[:])[:12])
+}
+
+// slug returns the last element of source, e.g. gitignore for SourceRepo,
+// with every character that is not a letter or a digit replaced.
+func slug(source string) string {
+	base := strings.TrimSuffix(filepath.Base(filepath.ToSlash(strings.TrimRight(source, `/\`))), ".git")
+	base = base[strings.LastIndexAny(base, ":/")+1:]
+
+	s := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' {
+			return r
+		}
+
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+
+		return '-'
+	}, base)
+
+	if s == "" {
+		return "source"
+	}
+
+	return s
+}
+
+// MigrateCache moves a repository of source that was cached directly in root,
+// before every source had its own directory, to CachePath of source.
+// A repository of another remote is left alone.
+func MigrateCache(root, source string, opts ...Option) error {
+	if _, err := git.PlainOpen(root); err != nil {
+		return nil
+	}
+
+	l, err := newOptions(opts).lock(root, true)
+	if err != nil {
+		return err
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	r, err := git.PlainOpen(root)
+	if err != nil {
+		// Another process migrated it while we were waiting for the lock
+		// or it is broken, which is left to Check and Repair.
+		return nil
+	}
+
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return nil
+	}
+
+	path := CachePath(root, source)
+	if CachePath(root, remote.Config().URLs[0]) != path {
+		return nil
+	}
+
+	tmp := filepath.Clean(root) + ".migrate"
+	if err := os.Rename(root, tmp); err != nil {
+		return errors.Wrap(err, "repo: move cache aside")
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil { //nolint:gomnd
+		return errors.Wrap(err, "repo: create cache directory")
+	}
+
+	return errors.Wrap(os.Rename(tmp, path), "repo: move cache")
+}
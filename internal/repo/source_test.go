@@ -0,0 +1,79 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSource(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "https", source: repo.SourceRepo, want: repo.SourceRepo},
+		{name: "file URL", source: "file:///srv/gitignore.git", want: "file:///srv/gitignore.git"},
+		{name: "scp-like", source: "git@example.com:mirrors/gitignore.git", want: "git@example.com:mirrors/gitignore.git"},
+		{name: "relative path", source: "testdata/gitignore", want: filepath.Join(wd, "testdata", "gitignore")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.NormalizeSource(tt.source)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	github := repo.CachePath("/cache", repo.SourceRepo)
+	mirror := repo.CachePath("/cache", "https://git.example.com/mirrors/gitignore.git")
+
+	assert.Equal(t, "/cache", filepath.Dir(github))
+	assert.Regexp(t, `^gitignore-[0-9a-f]{12}$`, filepath.Base(github))
+	assert.NotEqual(t, github, mirror)
+	assert.Equal(t, github, repo.CachePath("/cache", repo.SourceRepo))
+}
+
+func TestMigrateCache(t *testing.T) {
+	source := newSourceRepo(t)
+	want := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "cache")
+
+	_, err = repo.New(root, source.dir)
+	require.NoError(t, err)
+
+	// A repository of another source is not touched.
+	assert.NoError(t, repo.MigrateCache(root, repo.SourceRepo))
+	assert.FileExists(t, filepath.Join(root, "HEAD"))
+
+	assert.NoError(t, repo.MigrateCache(root, source.dir))
+
+	r, err := repo.Open(repo.CachePath(root, source.dir))
+	require.NoError(t, err)
+
+	got, _, err := repo.Checkout(r, "")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// Nothing to migrate anymore.
+	assert.NoError(t, repo.MigrateCache(root, source.dir))
+	assert.NoFileExists(t, filepath.Join(root, "HEAD"))
+	assert.DirExists(t, repo.CachePath(root, source.dir))
+}
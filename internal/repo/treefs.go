@@ -0,0 +1,213 @@
+package repo
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// treeFS is a read-only fs.FS of the files of a git tree.
+// Files are read straight from the object store so that
+// the worktree of the repository is never touched.
+type treeFS struct {
+	tree *object.Tree
+}
+
+// TreeFS returns a read-only fs.FS of the files in tree.
+func TreeFS(tree *object.Tree) fs.FS {
+	return &treeFS{tree: tree}
+}
+
+func (t *treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return t.openDir(name, t.tree)
+	}
+
+	entry, err := t.tree.FindEntry(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if entry.Mode == filemode.Dir {
+		subtree, err := t.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return t.openDir(name, subtree)
+	}
+
+	f, err := t.tree.TreeEntryFile(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	rc, err := f.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &treeFile{
+		ReadCloser: rc,
+		info:       &entryInfo{tree: t.tree, entry: *entry, size: f.Size},
+	}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := t.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	dir, ok := f.(*treeDir)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	return dir.ReadDir(-1)
+}
+
+func (t *treeFS) openDir(name string, tree *object.Tree) (*treeDir, error) {
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+
+		entries = append(entries, &entryInfo{tree: tree, entry: e, size: -1})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return &treeDir{
+		info: &entryInfo{
+			entry: object.TreeEntry{Name: path.Base(name), Mode: filemode.Dir, Hash: tree.Hash},
+		},
+		entries: entries,
+	}, nil
+}
+
+type treeFile struct {
+	io.ReadCloser
+	info *entryInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+type treeDir struct {
+	info    *entryInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *treeDir) Close() error {
+	return nil
+}
+
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+
+	if n <= 0 {
+		d.offset = len(d.entries)
+
+		return rest, nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(rest) {
+		n = len(rest)
+	}
+
+	d.offset += n
+
+	return rest[:n], nil
+}
+
+// entryInfo implements both fs.FileInfo and fs.DirEntry for a tree entry.
+type entryInfo struct {
+	tree  *object.Tree
+	entry object.TreeEntry
+	size  int64
+}
+
+func (e *entryInfo) Name() string {
+	return e.entry.Name
+}
+
+func (e *entryInfo) Size() int64 {
+	if e.IsDir() {
+		return 0
+	}
+
+	if e.size >= 0 {
+		return e.size
+	}
+
+	if f, err := e.tree.TreeEntryFile(&e.entry); err == nil {
+		e.size = f.Size
+	}
+
+	return e.size
+}
+
+func (e *entryInfo) Mode() fs.FileMode {
+	switch e.entry.Mode {
+	case filemode.Dir:
+		return fs.ModeDir | 0555 //nolint:gomnd
+	case filemode.Executable:
+		return 0555 //nolint:gomnd
+	case filemode.Symlink:
+		return fs.ModeSymlink | 0444 //nolint:gomnd
+	default:
+		return 0444 //nolint:gomnd
+	}
+}
+
+func (e *entryInfo) Type() fs.FileMode {
+	return e.Mode().Type()
+}
+
+func (e *entryInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (e *entryInfo) IsDir() bool {
+	return e.entry.Mode == filemode.Dir
+}
+
+func (e *entryInfo) Sys() interface{} {
+	return e.entry
+}
+
+func (e *entryInfo) Info() (fs.FileInfo, error) {
+	return e, nil
+}
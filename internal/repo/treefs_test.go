@@ -0,0 +1,40 @@
+package repo_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeFS(t *testing.T) {
+	source := newSourceRepo(t)
+	hash := source.commit(map[string]string{
+		"order":                   "go\n",
+		"templates/Go.gitignore":  "*.exe\n",
+		"templates/Go.patch":      "/vendor/\n",
+		"templates/Elm.gitignore": "elm-stuff\n",
+	})
+
+	commit, err := source.repo.CommitObject(plumbing.NewHash(hash))
+	require.NoError(t, err)
+
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+
+	fsys := repo.TreeFS(tree)
+
+	assert.NoError(t, fstest.TestFS(fsys,
+		"order", "templates/Go.gitignore", "templates/Go.patch", "templates/Elm.gitignore"))
+
+	content, err := fs.ReadFile(fsys, "templates/Go.patch")
+	assert.NoError(t, err)
+	assert.Equal(t, "/vendor/\n", string(content))
+
+	_, err = fs.ReadFile(fsys, "templates/Rust.gitignore")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
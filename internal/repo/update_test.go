@@ -0,0 +1,67 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	source := newSourceRepo(t)
+	first := source.commit(map[string]string{
+		"templates/Go.gitignore":  "*.exe\n",
+		"templates/Elm.gitignore": "elm-stuff\n",
+	})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	r, err := repo.New(dir, source.dir)
+	require.NoError(t, err)
+
+	cloned, err := repo.LastFetch(r)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), cloned, time.Minute)
+
+	old, current, err := repo.Update(r)
+	assert.NoError(t, err)
+	assert.Equal(t, first, old)
+	assert.Equal(t, first, current)
+
+	second := source.commit(map[string]string{
+		"templates/Go.gitignore":   "*.exe\n*.test\n",
+		"templates/Rust.gitignore": "target/\n",
+	})
+
+	old, current, err = repo.Update(r)
+	assert.NoError(t, err)
+	assert.Equal(t, first, old)
+	assert.Equal(t, second, current)
+
+	got, _, err := repo.Checkout(r, "")
+	require.NoError(t, err)
+	assert.Equal(t, second, got)
+
+	changes, err := repo.Diff(r, old, current)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []repo.Change{
+		{Action: "modified", Path: "templates/Go.gitignore"},
+		{Action: "added", Path: "templates/Rust.gitignore"},
+	}, changes)
+}
+
+func TestLastFetch_Unknown(t *testing.T) {
+	source := newSourceRepo(t)
+	source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	got, err := repo.LastFetch(source.repo)
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+}
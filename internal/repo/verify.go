@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrUntrusted is returned when a commit is not signed by one of
+// the keys given with WithTrustedKeys.
+var ErrUntrusted = errors.New("not signed by a trusted key")
+
+// Verify checks that the commit hash of r is signed by one of the keys
+// given with WithTrustedKeys and returns the signer, the primary identity
+// and the fingerprint of the key. It returns an empty signer without
+// checking anything when no keys are given.
+func Verify(r *git.Repository, hash string, opts ...Option) (string, error) {
+	return newOptions(opts).verify(r, hash)
+}
+
+func (o *options) verify(r *git.Repository, hash string) (string, error) {
+	if o.trustedKeys == "" {
+		return "", nil
+	}
+
+	if _, err := openpgp.ReadArmoredKeyRing(strings.NewReader(o.trustedKeys)); err != nil {
+		return "", errors.Wrap(err, "repo: read trusted keys")
+	}
+
+	commit, err := r.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return "", errors.Wrapf(err, "repo: get commit %s", hash)
+	}
+
+	if commit.PGPSignature == "" {
+		return "", errors.Wrapf(ErrUntrusted, "repo: commit %s", hash)
+	}
+
+	entity, err := commit.Verify(o.trustedKeys)
+	if err != nil {
+		return "", errors.Wrapf(ErrUntrusted, "repo: commit %s: %s", hash, err)
+	}
+
+	return signer(entity), nil
+}
+
+// signer describes entity as e.g. "gig <gig@example.com> (key 0123...)".
+func signer(entity *openpgp.Entity) string {
+	name := "unknown"
+	if id := entity.PrimaryIdentity(); id != nil {
+		name = id.Name
+	}
+
+	return fmt.Sprintf("%s (key %X)", name, entity.PrimaryKey.Fingerprint)
+}
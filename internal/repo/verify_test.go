@@ -0,0 +1,124 @@
+package repo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/shihanng/gig/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newKey generates a small key, good enough for the tests only.
+func newKey(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	require.NoError(t, err)
+
+	return e
+}
+
+// armoredPublicKey returns the public key of e as an armored keyring.
+func armoredPublicKey(t *testing.T, e *openpgp.Entity) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	require.NoError(t, w.Close())
+
+	return buf.String()
+}
+
+func TestVerify(t *testing.T) {
+	trusted := newKey(t, "trusted")
+	untrusted := newKey(t, "untrusted")
+
+	source := newSourceRepo(t)
+
+	source.signKey = trusted
+	signed := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	source.signKey = nil
+	unsigned := source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	source.signKey = untrusted
+	foreign := source.commit(map[string]string{"templates/Go.gitignore": "*.out\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	r, err := repo.New(filepath.Join(dir, "cache"), source.dir)
+	require.NoError(t, err)
+
+	keys := repo.WithTrustedKeys(armoredPublicKey(t, trusted))
+
+	var verified string
+
+	hash, _, err := repo.Checkout(r, signed, keys, repo.WithVerified(func(signer string) { verified = signer }))
+	assert.NoError(t, err)
+	assert.Equal(t, signed, hash)
+	assert.Contains(t, verified, "trusted <trusted@example.com> (key ")
+
+	signer, err := repo.Verify(r, signed, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, verified, signer)
+
+	for _, rev := range []string{unsigned, foreign} {
+		_, _, err := repo.Checkout(r, rev, keys)
+		assert.ErrorIs(t, err, repo.ErrUntrusted)
+	}
+
+	// Without trusted keys every commit is used.
+	_, _, err = repo.Checkout(r, foreign)
+	assert.NoError(t, err)
+
+	signer, err = repo.Verify(r, foreign)
+	assert.NoError(t, err)
+	assert.Empty(t, signer)
+
+	_, err = repo.Verify(r, signed, repo.WithTrustedKeys("not a keyring"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, repo.ErrUntrusted)
+}
+
+func TestUpdate_Untrusted(t *testing.T) {
+	trusted := newKey(t, "trusted")
+
+	source := newSourceRepo(t)
+
+	source.signKey = trusted
+	signed := source.commit(map[string]string{"templates/Go.gitignore": "*.exe\n"})
+
+	dir, err := ioutil.TempDir("", "gig")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	r, err := repo.New(filepath.Join(dir, "cache"), source.dir)
+	require.NoError(t, err)
+
+	source.signKey = nil
+	source.commit(map[string]string{"templates/Go.gitignore": "*.test\n"})
+
+	keys := repo.WithTrustedKeys(armoredPublicKey(t, trusted))
+
+	_, _, err = repo.Update(r, keys)
+	assert.ErrorIs(t, err, repo.ErrUntrusted)
+
+	// The branch still points to the trusted commit.
+	hash, _, err := repo.Checkout(r, "", keys)
+	assert.NoError(t, err)
+	assert.Equal(t, signed, hash)
+}